@@ -0,0 +1,134 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/pagination"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WebhookEvent struct {
+	ID          uuid.UUID       `json:"id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	Provider    string          `json:"provider"`
+	EventID     string          `json:"event_id"`
+	EventType   string          `json:"event_type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Error       *string         `json:"error"`
+	Attempts    int32           `json:"attempts"`
+	ProcessedAt *time.Time      `json:"processed_at"`
+}
+
+type WebhookEventPage struct {
+	Events     []WebhookEvent `json:"events"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+func webhookEventFromDB(ev database.WebhookEvent) WebhookEvent {
+	event := WebhookEvent{
+		ID:          ev.ID,
+		CreatedAt:   ev.CreatedAt,
+		UpdatedAt:   ev.UpdatedAt,
+		Provider:    ev.Provider,
+		EventID:     ev.EventID,
+		EventType:   ev.EventType,
+		Payload:     ev.Payload,
+		Status:      ev.Status,
+		Attempts:    ev.Attempts,
+		ProcessedAt: nullTimePtr(ev.ProcessedAt),
+	}
+	if ev.Error.Valid {
+		event.Error = &ev.Error.String
+	}
+	return event
+}
+
+// requireAdmin checks the ADMIN_API_KEY sent as "Authorization: ApiKey <key>".
+// Admin endpoints are disabled while no key is configured.
+func (cfg *apiConfig) requireAdmin(w http.ResponseWriter, req *http.Request) bool {
+	apiKey, err := auth.GetAPIKey(req.Header)
+	if err != nil {
+		respondWithError(w, 401, "Error fetching API key")
+		return false
+	}
+	if !auth.CompareAPIKey(apiKey, cfg.adminKey) {
+		respondWithError(w, 401, "Invalid API Key")
+		return false
+	}
+	return true
+}
+
+func (cfg *apiConfig) handlerAdminWebhookEventsList(w http.ResponseWriter, req *http.Request) {
+	if !cfg.requireAdmin(w, req) {
+		return
+	}
+	limit, err := pagination.Limit(req.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	params := database.ListWebhookEventsParams{MaxResults: int32(limit)}
+	if status := req.URL.Query().Get("status"); status != "" {
+		params.Status = sql.NullString{String: status, Valid: true}
+	}
+	if c := req.URL.Query().Get("cursor"); c != "" {
+		cursor, err := pagination.Decode(c)
+		if err != nil {
+			respondWithError(w, 400, err.Error())
+			return
+		}
+		params.BeforeCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	events, err := cfg.dbQueries.ListWebhookEvents(req.Context(), params)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	page := WebhookEventPage{Events: make([]WebhookEvent, len(events))}
+	for i, ev := range events {
+		page.Events[i] = webhookEventFromDB(ev)
+	}
+	if len(events) == limit {
+		last := events[len(events)-1]
+		page.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	respondWithJSON(w, 200, page)
+}
+
+// handlerAdminWebhookEventReplay processes a failed event again and returns
+// it with its new status.
+func (cfg *apiConfig) handlerAdminWebhookEventReplay(w http.ResponseWriter, req *http.Request) {
+	if !cfg.requireAdmin(w, req) {
+		return
+	}
+	id, err := uuid.Parse(req.PathValue("eventID"))
+	if err != nil {
+		respondWithError(w, 404, "Webhook event not found")
+		return
+	}
+	ev, err := cfg.dbQueries.GetWebhookEvent(req.Context(), id)
+	if err != nil {
+		respondWithError(w, 404, "Webhook event not found")
+		return
+	}
+	if ev.Status != webhookFailed && ev.Status != webhookReceived {
+		respondWithError(w, 409, "Only failed or unprocessed webhook events can be replayed")
+		return
+	}
+	// A failure is recorded on the event itself, which is what's returned.
+	_ = cfg.processPolkaEvent(req.Context(), ev.ID)
+	ev, err = cfg.dbQueries.GetWebhookEvent(req.Context(), id)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	respondWithJSON(w, 200, webhookEventFromDB(ev))
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Relationship struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RelationshipRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// relationshipTarget decodes the target user of a block or mute request and
+// makes sure it exists and isn't the requesting user.
+func (cfg *apiConfig) relationshipTarget(w http.ResponseWriter, req *http.Request, userID uuid.UUID) (uuid.UUID, bool) {
+	target := RelationshipRequest{}
+	decoder := json.NewDecoder(req.Body)
+	err := decoder.Decode(&target)
+	if err != nil {
+		respondWithError(w, 400, "Error decoding request body")
+		return uuid.Nil, false
+	}
+	if target.UserID == userID {
+		respondWithError(w, 400, "You can't do that to yourself")
+		return uuid.Nil, false
+	}
+	if _, err = cfg.dbQueries.GetUserByID(req.Context(), target.UserID); err != nil {
+		respondWithError(w, 404, "User not found")
+		return uuid.Nil, false
+	}
+	return target.UserID, true
+}
+
+func (cfg *apiConfig) handlerBlockCreate(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	targetID, ok := cfg.relationshipTarget(w, req, userID)
+	if !ok {
+		return
+	}
+	block, err := cfg.dbQueries.CreateBlock(req.Context(), database.CreateBlockParams{BlockerID: userID, BlockedID: targetID})
+	if err != nil {
+		respondWithError(w, 500, "Error blocking user")
+		return
+	}
+	respondWithJSON(w, 201, Relationship{UserID: block.BlockedID, CreatedAt: block.CreatedAt})
+}
+
+func (cfg *apiConfig) handlerBlockDelete(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	targetID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	err = cfg.dbQueries.DeleteBlock(req.Context(), database.DeleteBlockParams{BlockerID: userID, BlockedID: targetID})
+	if err != nil {
+		respondWithError(w, 500, "Error unblocking user")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
+
+func (cfg *apiConfig) handlerBlocksList(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	blocks, err := cfg.dbQueries.ListBlocks(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	relationships := make([]Relationship, len(blocks))
+	for i := range blocks {
+		relationships[i] = Relationship{UserID: blocks[i].BlockedID, CreatedAt: blocks[i].CreatedAt}
+	}
+	respondWithJSON(w, 200, relationships)
+}
+
+func (cfg *apiConfig) handlerMuteCreate(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	targetID, ok := cfg.relationshipTarget(w, req, userID)
+	if !ok {
+		return
+	}
+	mute, err := cfg.dbQueries.CreateMute(req.Context(), database.CreateMuteParams{MuterID: userID, MutedID: targetID})
+	if err != nil {
+		respondWithError(w, 500, "Error muting user")
+		return
+	}
+	respondWithJSON(w, 201, Relationship{UserID: mute.MutedID, CreatedAt: mute.CreatedAt})
+}
+
+func (cfg *apiConfig) handlerMuteDelete(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	targetID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	err = cfg.dbQueries.DeleteMute(req.Context(), database.DeleteMuteParams{MuterID: userID, MutedID: targetID})
+	if err != nil {
+		respondWithError(w, 500, "Error unmuting user")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
+
+func (cfg *apiConfig) handlerMutesList(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	mutes, err := cfg.dbQueries.ListMutes(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	relationships := make([]Relationship, len(mutes))
+	for i := range mutes {
+		relationships[i] = Relationship{UserID: mutes[i].MutedID, CreatedAt: mutes[i].CreatedAt}
+	}
+	respondWithJSON(w, 200, relationships)
+}
+
+// hideBlockedAndMuted drops chirps whose authors the viewer has blocked or
+// muted. Anonymous viewers see everything.
+func (cfg *apiConfig) hideBlockedAndMuted(ctx context.Context, viewerID uuid.UUID, chirps []database.Chirp) ([]database.Chirp, error) {
+	if viewerID == uuid.Nil {
+		return chirps, nil
+	}
+	hidden, err := cfg.dbQueries.ListHiddenAuthors(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(hidden) == 0 {
+		return chirps, nil
+	}
+	return slices.DeleteFunc(chirps, func(c database.Chirp) bool {
+		return slices.Contains(hidden, c.UserID)
+	}), nil
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"chirpy/internal/chirptext"
+	"chirpy/internal/database"
+	"chirpy/internal/pagination"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const maxCollectionNameLength = 50
+
+type Bookmark struct {
+	ID           uuid.UUID  `json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CollectionID *uuid.UUID `json:"collection_id"`
+	Chirp        Chirp      `json:"chirp"`
+}
+
+type BookmarkPage struct {
+	Bookmarks  []Bookmark `json:"bookmarks"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+type BookmarkRequest struct {
+	CollectionID *uuid.UUID `json:"collection_id"`
+}
+
+type Collection struct {
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Name          string    `json:"name"`
+	BookmarkCount int64     `json:"bookmark_count"`
+}
+
+type CollectionRequest struct {
+	Name string `json:"name"`
+}
+
+// collectionName decodes and validates the name of a collection.
+func collectionName(w http.ResponseWriter, req *http.Request) (string, bool) {
+	collReq := CollectionRequest{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&collReq); err != nil {
+		respondWithError(w, 400, "Error decoding request body")
+		return "", false
+	}
+	name := strings.TrimSpace(collReq.Name)
+	if name == "" {
+		respondWithError(w, 400, "Collection name can't be empty")
+		return "", false
+	}
+	if chirptext.Length(name) > maxCollectionNameLength {
+		respondWithError(w, 400, "Collection name is too long")
+		return "", false
+	}
+	return name, true
+}
+
+// handlerBookmarkCreate bookmarks a chirp, optionally in one of the user's
+// collections. Bookmarking it again moves it to the given collection.
+func (cfg *apiConfig) handlerBookmarkCreate(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	bookmarkReq := BookmarkRequest{}
+	if req.ContentLength != 0 {
+		decoder := json.NewDecoder(req.Body)
+		if err = decoder.Decode(&bookmarkReq); err != nil {
+			respondWithError(w, 400, "Error decoding request body")
+			return
+		}
+	}
+	dbChirp, err := cfg.dbQueries.GetChirp(req.Context(), chirpID)
+	if err != nil || !canView(dbChirp, userID, true) {
+		respondWithError(w, 404, "Chirp doesn't exist")
+		return
+	}
+	blocked, err := cfg.dbQueries.IsBlockedBetween(req.Context(), database.IsBlockedBetweenParams{UserA: userID, UserB: dbChirp.UserID})
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	if blocked {
+		respondWithError(w, 403, "You can't bookmark this chirp")
+		return
+	}
+	collectionID := uuid.NullUUID{}
+	if bookmarkReq.CollectionID != nil {
+		_, err = cfg.dbQueries.GetBookmarkCollection(req.Context(), database.GetBookmarkCollectionParams{ID: *bookmarkReq.CollectionID, UserID: userID})
+		if err != nil {
+			respondWithError(w, 404, "Collection not found")
+			return
+		}
+		collectionID = uuid.NullUUID{UUID: *bookmarkReq.CollectionID, Valid: true}
+	}
+	b, err := cfg.dbQueries.CreateBookmark(req.Context(), database.CreateBookmarkParams{UserID: userID, ChirpID: chirpID, CollectionID: collectionID})
+	if err != nil {
+		respondWithError(w, 500, "Error bookmarking chirp")
+		return
+	}
+	chirps, err := cfg.renderChirps(req.Context(), userID, []database.Chirp{dbChirp})
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	respondWithJSON(w, 201, Bookmark{ID: b.ID, CreatedAt: b.CreatedAt, CollectionID: nullUUIDPtr(b.CollectionID), Chirp: chirps[0]})
+}
+
+func (cfg *apiConfig) handlerBookmarkDelete(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 404, "Bookmark not found")
+		return
+	}
+	deleted, err := cfg.dbQueries.DeleteBookmark(req.Context(), database.DeleteBookmarkParams{UserID: userID, ChirpID: chirpID})
+	if err != nil {
+		respondWithError(w, 500, "Error removing bookmark")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "Bookmark not found")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
+
+// handlerBookmarksList lists the user's bookmarks, newest first, optionally
+// limited to one collection. Bookmarks of deleted chirps are removed along
+// with them.
+func (cfg *apiConfig) handlerBookmarksList(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	limit, err := pagination.Limit(req.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	params := database.ListBookmarksParams{UserID: userID, MaxResults: int32(limit)}
+	if collection := req.URL.Query().Get("collection_id"); collection != "" {
+		collectionID, err := uuid.Parse(collection)
+		if err != nil {
+			respondWithError(w, 404, "Collection not found")
+			return
+		}
+		params.CollectionID = uuid.NullUUID{UUID: collectionID, Valid: true}
+	}
+	if c := req.URL.Query().Get("cursor"); c != "" {
+		cursor, err := pagination.Decode(c)
+		if err != nil {
+			respondWithError(w, 400, err.Error())
+			return
+		}
+		params.BeforeCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	rows, err := cfg.dbQueries.ListBookmarks(req.Context(), params)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	// Bookmarks work like direct links, so unlisted chirps stay visible, but
+	// followers-only chirps still need the viewer to follow the author, and
+	// following can end after the bookmark was made.
+	visible := slices.DeleteFunc(slices.Clone(rows), func(row database.ListBookmarksRow) bool {
+		return !canView(row.Chirp, userID, true)
+	})
+	dbChirps := make([]database.Chirp, len(visible))
+	for i, row := range visible {
+		dbChirps[i] = row.Chirp
+	}
+	chirps, err := cfg.renderChirps(req.Context(), userID, dbChirps)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	page := BookmarkPage{Bookmarks: make([]Bookmark, len(visible))}
+	for i, row := range visible {
+		page.Bookmarks[i] = Bookmark{ID: row.ID, CreatedAt: row.CreatedAt, CollectionID: nullUUIDPtr(row.CollectionID), Chirp: chirps[i]}
+	}
+	if len(rows) == limit {
+		last := rows[len(rows)-1]
+		page.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	respondWithJSON(w, 200, page)
+}
+
+func (cfg *apiConfig) handlerCollectionsCreate(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	name, ok := collectionName(w, req)
+	if !ok {
+		return
+	}
+	c, err := cfg.dbQueries.CreateBookmarkCollection(req.Context(), database.CreateBookmarkCollectionParams{UserID: userID, Name: name})
+	if err != nil {
+		respondWithError(w, 409, "You already have a collection with that name")
+		return
+	}
+	respondWithJSON(w, 201, Collection{ID: c.ID, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt, Name: c.Name})
+}
+
+func (cfg *apiConfig) handlerCollectionsList(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	rows, err := cfg.dbQueries.ListBookmarkCollections(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	collections := make([]Collection, len(rows))
+	for i, row := range rows {
+		collections[i] = Collection{ID: row.ID, CreatedAt: row.CreatedAt, UpdatedAt: row.UpdatedAt, Name: row.Name, BookmarkCount: row.BookmarkCount}
+	}
+	respondWithJSON(w, 200, collections)
+}
+
+func (cfg *apiConfig) handlerCollectionsUpdate(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	collectionID, err := uuid.Parse(req.PathValue("collectionID"))
+	if err != nil {
+		respondWithError(w, 404, "Collection not found")
+		return
+	}
+	name, ok := collectionName(w, req)
+	if !ok {
+		return
+	}
+	if _, err = cfg.dbQueries.GetBookmarkCollection(req.Context(), database.GetBookmarkCollectionParams{ID: collectionID, UserID: userID}); err != nil {
+		respondWithError(w, 404, "Collection not found")
+		return
+	}
+	c, err := cfg.dbQueries.RenameBookmarkCollection(req.Context(), database.RenameBookmarkCollectionParams{ID: collectionID, UserID: userID, Name: name})
+	if err != nil {
+		respondWithError(w, 409, "You already have a collection with that name")
+		return
+	}
+	respondWithJSON(w, 200, Collection{ID: c.ID, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt, Name: c.Name})
+}
+
+// handlerCollectionsDelete deletes a collection. Its bookmarks are kept,
+// outside of any collection.
+func (cfg *apiConfig) handlerCollectionsDelete(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	collectionID, err := uuid.Parse(req.PathValue("collectionID"))
+	if err != nil {
+		respondWithError(w, 404, "Collection not found")
+		return
+	}
+	deleted, err := cfg.dbQueries.DeleteBookmarkCollection(req.Context(), database.DeleteBookmarkCollectionParams{ID: collectionID, UserID: userID})
+	if err != nil {
+		respondWithError(w, 500, "Error deleting collection")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "Collection not found")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
+
+func nullUUIDPtr(u uuid.NullUUID) *uuid.UUID {
+	if !u.Valid {
+		return nil
+	}
+	return &u.UUID
+}
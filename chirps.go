@@ -0,0 +1,368 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/chirptext"
+	"chirpy/internal/database"
+	"chirpy/internal/polls"
+	"chirpy/internal/stream"
+	"chirpy/internal/visibility"
+	"chirpy/internal/webhooks"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ChirpRequest struct {
+	Body     string       `json:"body"`
+	MediaIDs []uuid.UUID  `json:"media_ids"`
+	Poll     *PollRequest `json:"poll"`
+	// Visibility is "public" (the default), "followers" or "unlisted".
+	Visibility string `json:"visibility"`
+	// PublishAt schedules the chirp instead of publishing it right away.
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// renderChirps converts chirps to their JSON form, loading the media and polls
+// attached to all of them in one batch. Poll results and the bookmarked flag
+// are as seen by viewerID; pass uuid.Nil for anonymous viewers.
+func (cfg *apiConfig) renderChirps(ctx context.Context, viewerID uuid.UUID, dbChirps []database.Chirp) ([]Chirp, error) {
+	chirps := make([]Chirp, len(dbChirps))
+	if len(dbChirps) == 0 {
+		return chirps, nil
+	}
+	ids := make([]uuid.UUID, len(dbChirps))
+	for i := range dbChirps {
+		ids[i] = dbChirps[i].ID
+	}
+	rows, err := cfg.dbQueries.ListMediaForChirps(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	dbMedia := make([]database.Media, len(rows))
+	for i, row := range rows {
+		dbMedia[i] = row.Media
+	}
+	rendered, err := cfg.renderMedia(ctx, dbMedia)
+	if err != nil {
+		return nil, err
+	}
+	media := map[uuid.UUID][]Media{}
+	for i, row := range rows {
+		media[row.ChirpID] = append(media[row.ChirpID], rendered[i])
+	}
+	chirpPolls, err := cfg.renderPolls(ctx, viewerID, ids)
+	if err != nil {
+		return nil, err
+	}
+	bookmarked := map[uuid.UUID]bool{}
+	if viewerID != uuid.Nil {
+		bookmarkedIDs, err := cfg.dbQueries.ListBookmarkedChirps(ctx, database.ListBookmarkedChirpsParams{UserID: viewerID, ChirpIds: ids})
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range bookmarkedIDs {
+			bookmarked[id] = true
+		}
+	}
+	pinnedIDs, err := cfg.dbQueries.ListPinnedAmong(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range dbChirps {
+		chirps[i] = chirpJSON(c)
+		chirps[i].Media = media[c.ID]
+		chirps[i].Poll = chirpPolls[c.ID]
+		chirps[i].Bookmarked = bookmarked[c.ID]
+		chirps[i].Pinned = slices.Contains(pinnedIDs, c.ID)
+		if chirps[i].Media == nil {
+			chirps[i].Media = []Media{}
+		}
+	}
+	return chirps, nil
+}
+
+// chirpJSON converts the columns of a chirp to its JSON form, without the
+// media, poll and viewer flags renderChirps loads.
+func chirpJSON(c database.Chirp) Chirp {
+	return Chirp{
+		ID:         c.ID,
+		CreatedAt:  c.CreatedAt,
+		UpdatedAt:  c.UpdatedAt,
+		Body:       c.Body,
+		UserID:     c.UserID,
+		Visibility: c.Visibility,
+	}
+}
+
+func (r ChirpRequest) visibility() string {
+	if r.Visibility == "" {
+		return visibility.Public
+	}
+	return r.Visibility
+}
+
+// canView applies a chirp's visibility for viewerID. direct is true when the
+// chirp was asked for by ID. Chirpy has no follows yet, so followers-only
+// chirps are only visible to their author.
+func canView(c database.Chirp, viewerID uuid.UUID, direct bool) bool {
+	return visibility.CanView(c.Visibility, c.UserID, viewerID, false, direct)
+}
+
+// visibleChirps drops the chirps viewerID isn't allowed to see.
+func visibleChirps(viewerID uuid.UUID, dbChirps []database.Chirp, direct bool) []database.Chirp {
+	return slices.DeleteFunc(dbChirps, func(c database.Chirp) bool {
+		return !canView(c, viewerID, direct)
+	})
+}
+
+// validateChirp checks a chirp against the author's limits as of publishAt,
+// which is when its poll starts running.
+func (cfg *apiConfig) validateChirp(ctx context.Context, userID uuid.UUID, chirpReq ChirpRequest, publishAt time.Time) error {
+	author, err := cfg.dbQueries.GetUserByID(ctx, userID)
+	if err != nil {
+		return &requestError{401, "User not found"}
+	}
+	caps, err := cfg.capabilities(ctx, author)
+	if err != nil {
+		return err
+	}
+	if chirptext.Length(chirpReq.Body) > caps.MaxChirpLength {
+		return &requestError{400, "Chirp is too long"}
+	}
+	if chirpReq.Visibility != "" && !visibility.Valid(chirpReq.Visibility) {
+		return &requestError{400, "Invalid visibility"}
+	}
+	if err = cfg.attachableMedia(ctx, userID, chirpReq.MediaIDs, caps.MaxMedia); err != nil {
+		return err
+	}
+	if chirpReq.Poll != nil {
+		if err = polls.Validate(chirpReq.Poll.Options, chirpReq.Poll.ExpiresAt, publishAt); err != nil {
+			return &requestError{400, err.Error()}
+		}
+	}
+	return nil
+}
+
+// insertChirp creates a validated chirp along with its media attachments and
+// poll, and queues its webhooks, outbox event and stream event. q should be
+// bound to a transaction.
+func insertChirp(ctx context.Context, q *database.Queries, userID uuid.UUID, chirpReq ChirpRequest) (database.Chirp, error) {
+	c, err := q.CreateChirp(ctx, database.CreateChirpParams{Body: cleanChirp(chirpReq.Body), UserID: userID, Visibility: chirpReq.visibility()})
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	for i, mediaID := range chirpReq.MediaIDs {
+		err = q.AttachMediaToChirp(ctx, database.AttachMediaToChirpParams{ChirpID: c.ID, MediaID: mediaID, Position: int32(i)})
+		if err != nil {
+			return database.Chirp{}, errMediaAttached
+		}
+	}
+	if chirpReq.Poll != nil {
+		if err = createPoll(ctx, q, c.ID, *chirpReq.Poll); err != nil {
+			return database.Chirp{}, err
+		}
+	}
+	if err = enqueueWebhook(ctx, q, webhooks.EventChirpCreated, userID, chirpData(c)); err != nil {
+		return database.Chirp{}, err
+	}
+	if err = recordOutbox(ctx, q, "chirp", c.ID, outboxChirpCreated, chirpData(c)); err != nil {
+		return database.Chirp{}, err
+	}
+	// Last, since the event log is locked until the transaction ends.
+	if err = recordChirpEvent(ctx, q, stream.EventChirpCreated, c); err != nil {
+		return database.Chirp{}, err
+	}
+	return c, nil
+}
+
+func (cfg *apiConfig) handlerChirpsCreate(w http.ResponseWriter, req *http.Request) {
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, 400, "Error fetching authorization token")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, 401, err.Error())
+		return
+	}
+	chirpReq := ChirpRequest{}
+	decoder := json.NewDecoder(req.Body)
+	err = decoder.Decode(&chirpReq)
+	if err != nil {
+		respondWithError(w, 400, "Error decoding chirp")
+		return
+	}
+	// Scheduled chirps are charged when they're scheduled, since the
+	// scheduler publishes them without a request to limit.
+	if !cfg.rateLimit(w, req, userID) {
+		return
+	}
+	if chirpReq.PublishAt != nil {
+		cfg.scheduleChirp(w, req, userID, chirpReq)
+		return
+	}
+	if err = cfg.validateChirp(req.Context(), userID, chirpReq, time.Now()); err != nil {
+		respondWithRequestError(w, err)
+		return
+	}
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	c, err := insertChirp(req.Context(), cfg.dbQueries.WithTx(tx), userID, chirpReq)
+	if err != nil {
+		respondWithRequestError(w, err)
+		return
+	}
+	if err = tx.Commit(); err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	cfg.metrics.ChirpsCreated.Inc()
+	chirps, err := cfg.renderChirps(req.Context(), userID, []database.Chirp{c})
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	respondWithJSON(w, 201, chirps[0])
+}
+
+func (cfg *apiConfig) handlerChirpsList(w http.ResponseWriter, req *http.Request) {
+	var dbChirps []database.Chirp
+	var err error
+	author := req.URL.Query().Get("author_id")
+	authorID := uuid.Nil
+	if author == "" {
+		dbChirps, err = cfg.dbQueries.ListChirps(req.Context())
+		if err != nil {
+			respondWithError(w, 500, err.Error())
+			return
+		}
+	} else {
+		authorID, err = uuid.Parse(author)
+		if err != nil {
+			respondWithError(w, 400, "User not found")
+			return
+		}
+		dbChirps, err = cfg.dbQueries.ListChirpsFromAuthor(req.Context(), authorID)
+		if err != nil {
+			respondWithError(w, 500, err.Error())
+			return
+		}
+	}
+	viewerID := cfg.viewerID(req)
+	dbChirps, err = cfg.hideBlockedAndMuted(req.Context(), viewerID, dbChirps)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	dbChirps = visibleChirps(viewerID, dbChirps, false)
+	// An author's pinned chirps come first, in their chosen order, whatever
+	// the sort.
+	var pinned []database.Chirp
+	if authorID != uuid.Nil {
+		pinned, dbChirps, err = cfg.splitPinned(req.Context(), authorID, dbChirps)
+		if err != nil {
+			respondWithError(w, 500, err.Error())
+			return
+		}
+	}
+	chirps, err := cfg.renderChirps(req.Context(), viewerID, append(pinned, dbChirps...))
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	sort := req.URL.Query().Get("sort")
+	switch sort {
+	case "", "asc":
+		respondWithJSON(w, 200, chirps)
+	case "desc":
+		slices.Reverse(chirps[len(pinned):])
+		respondWithJSON(w, 200, chirps)
+	}
+}
+
+func (cfg *apiConfig) handlerChirpsGet(w http.ResponseWriter, req *http.Request) {
+	chirpIDstring := req.PathValue("chirpID")
+	chirpID, err := uuid.Parse(chirpIDstring)
+	if err != nil {
+		respondWithError(w, 400, "Invalid ChirpID")
+		return
+	}
+	dbChirp, err := cfg.dbQueries.GetChirp(req.Context(), chirpID)
+	viewerID := cfg.viewerID(req)
+	if err != nil || !canView(dbChirp, viewerID, true) {
+		respondWithError(w, 404, "Chirp doesn't exist")
+		return
+	}
+	chirps, err := cfg.renderChirps(req.Context(), viewerID, []database.Chirp{dbChirp})
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	respondWithJSON(w, 200, chirps[0])
+}
+
+func (cfg *apiConfig) handlerChirpsDelete(w http.ResponseWriter, req *http.Request) {
+	bearerToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, 401, "Error fetching authorization token")
+		return
+	}
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization token")
+		return
+	}
+	chirpIDstring := req.PathValue("chirpID")
+	chirpID, err := uuid.Parse(chirpIDstring)
+	if err != nil {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	dbChirp, err := cfg.dbQueries.GetChirp(req.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, 404, "Chirp doesn't exist")
+		return
+	}
+	if userID != dbChirp.UserID {
+		respondWithError(w, 403, "You can't delete someone else's chirp")
+		return
+	}
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	if err = qtx.DeleteChirp(req.Context(), chirpID); err != nil {
+		respondWithError(w, 500, "Error deleting chirp")
+		return
+	}
+	if err = enqueueWebhook(req.Context(), qtx, webhooks.EventChirpDeleted, userID, chirpData(dbChirp)); err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	if err = recordOutbox(req.Context(), qtx, "chirp", dbChirp.ID, outboxChirpDeleted, chirpData(dbChirp)); err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	if err = recordChirpEvent(req.Context(), qtx, stream.EventChirpDeleted, dbChirp); err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	if err = tx.Commit(); err != nil {
+		respondWithError(w, 500, "Error deleting chirp")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
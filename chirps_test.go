@@ -0,0 +1,50 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/visibility"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestChirpJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		visibility string
+	}{
+		{name: "Public", visibility: visibility.Public},
+		{name: "Followers only", visibility: visibility.Followers},
+		{name: "Unlisted", visibility: visibility.Unlisted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := database.Chirp{
+				ID:         uuid.New(),
+				CreatedAt:  time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+				UpdatedAt:  time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+				Body:       "hello",
+				UserID:     uuid.New(),
+				Visibility: tt.visibility,
+			}
+			data, err := json.Marshal(chirpJSON(c))
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			var got struct {
+				ID         uuid.UUID `json:"id"`
+				Body       string    `json:"body"`
+				Visibility string    `json:"visibility"`
+			}
+			if err = json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if got.ID != c.ID || got.Body != c.Body || got.Visibility != tt.visibility {
+				t.Errorf("chirpJSON() = %s, want id %s, body %q and visibility %q", data, c.ID, c.Body, tt.visibility)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// runCommand runs a maintenance command given on the command line instead of
+// serving, and returns the exit code.
+//
+//	chirpy gc-tokens    delete stale refresh tokens now
+func (cfg *apiConfig) runCommand(ctx context.Context, args []string) int {
+	switch args[0] {
+	case "gc-tokens":
+		deleted, err := cfg.collectRefreshTokens(ctx)
+		fmt.Printf("Removed %d stale refresh tokens\n", deleted)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error removing refresh tokens: %s\n", err)
+			return 1
+		}
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\nUsage: chirpy [gc-tokens]\n", args[0])
+		return 2
+	}
+}
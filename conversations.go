@@ -0,0 +1,399 @@
+package main
+
+import (
+	"chirpy/internal/chirptext"
+	"chirpy/internal/database"
+	"chirpy/internal/pagination"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxConversationSize = 10
+	maxMessageLength    = 1000
+)
+
+type Conversation struct {
+	ID             uuid.UUID   `json:"id"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+	IsGroup        bool        `json:"is_group"`
+	ParticipantIDs []uuid.UUID `json:"participant_ids"`
+	LastReadAt     *time.Time  `json:"last_read_at"`
+	UnreadCount    int64       `json:"unread_count"`
+}
+
+type Message struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+}
+
+type MessagePage struct {
+	Messages   []Message `json:"messages"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+type ConversationRequest struct {
+	ParticipantIDs []uuid.UUID `json:"participant_ids"`
+}
+
+type MessageRequest struct {
+	Body string `json:"body"`
+}
+
+type ReadMarkerRequest struct {
+	MessageID uuid.UUID `json:"message_id"`
+}
+
+type UnreadCount struct {
+	UnreadCount int64 `json:"unread_count"`
+}
+
+func (cfg *apiConfig) handlerConversationCreate(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	convReq := ConversationRequest{}
+	decoder := json.NewDecoder(req.Body)
+	err := decoder.Decode(&convReq)
+	if err != nil {
+		respondWithError(w, 400, "Error decoding request body")
+		return
+	}
+	others := slices.DeleteFunc(slices.Clone(convReq.ParticipantIDs), func(id uuid.UUID) bool {
+		return id == userID
+	})
+	slices.SortFunc(others, func(a, b uuid.UUID) int { return slices.Compare(a[:], b[:]) })
+	others = slices.Compact(others)
+	if len(others) == 0 {
+		respondWithError(w, 400, "A conversation needs at least one other participant")
+		return
+	}
+	if len(others)+1 > maxConversationSize {
+		respondWithError(w, 400, "Too many participants")
+		return
+	}
+	for _, otherID := range others {
+		if _, err = cfg.dbQueries.GetUserByID(req.Context(), otherID); err != nil {
+			respondWithError(w, 404, "User not found")
+			return
+		}
+		blocked, err := cfg.dbQueries.IsBlockedBetween(req.Context(), database.IsBlockedBetweenParams{UserA: userID, UserB: otherID})
+		if err != nil {
+			respondWithError(w, 500, err.Error())
+			return
+		}
+		if blocked {
+			respondWithError(w, 403, "You can't message this user")
+			return
+		}
+	}
+	isGroup := len(others) > 1
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	if !isGroup {
+		// Locking both users, in a fixed order so concurrent requests
+		// can't deadlock, keeps two requests from each creating a direct
+		// conversation for the pair.
+		pair := []uuid.UUID{userID, others[0]}
+		slices.SortFunc(pair, func(a, b uuid.UUID) int { return slices.Compare(a[:], b[:]) })
+		for _, id := range pair {
+			if err = qtx.LockUser(req.Context(), id); err != nil {
+				respondWithError(w, 500, err.Error())
+				return
+			}
+		}
+		existing, err := qtx.GetDirectConversation(req.Context(), database.GetDirectConversationParams{UserA: userID, UserB: others[0]})
+		if err == nil {
+			tx.Rollback()
+			conversation, err := cfg.conversationForUser(req, existing.ID, userID)
+			if err != nil {
+				respondWithError(w, 500, err.Error())
+				return
+			}
+			respondWithJSON(w, 200, conversation)
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, 500, err.Error())
+			return
+		}
+	}
+	c, err := qtx.CreateConversation(req.Context(), isGroup)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	for _, participantID := range append([]uuid.UUID{userID}, others...) {
+		err = qtx.AddConversationParticipant(req.Context(), database.AddConversationParticipantParams{ConversationID: c.ID, UserID: participantID})
+		if err != nil {
+			respondWithError(w, 500, err.Error())
+			return
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	conversation, err := cfg.conversationForUser(req, c.ID, userID)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	respondWithJSON(w, 201, conversation)
+}
+
+func (cfg *apiConfig) handlerConversationsList(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	rows, err := cfg.dbQueries.ListConversationsForUser(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	conversations := make([]Conversation, len(rows))
+	for i, row := range rows {
+		participants, err := cfg.dbQueries.ListConversationParticipants(req.Context(), row.ID)
+		if err != nil {
+			respondWithError(w, 500, err.Error())
+			return
+		}
+		conversations[i] = Conversation{
+			ID:             row.ID,
+			CreatedAt:      row.CreatedAt,
+			UpdatedAt:      row.UpdatedAt,
+			IsGroup:        row.IsGroup,
+			ParticipantIDs: participants,
+			LastReadAt:     nullTimePtr(row.LastReadAt),
+			UnreadCount:    row.UnreadCount,
+		}
+	}
+	respondWithJSON(w, 200, conversations)
+}
+
+func (cfg *apiConfig) handlerConversationsUnread(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	count, err := cfg.dbQueries.CountUnreadMessages(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	respondWithJSON(w, 200, UnreadCount{UnreadCount: count})
+}
+
+func (cfg *apiConfig) handlerMessageCreate(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	conv, ok := cfg.participantConversation(w, req, userID)
+	if !ok {
+		return
+	}
+	msgReq := MessageRequest{}
+	decoder := json.NewDecoder(req.Body)
+	err := decoder.Decode(&msgReq)
+	if err != nil {
+		respondWithError(w, 400, "Error decoding message")
+		return
+	}
+	if msgReq.Body == "" {
+		respondWithError(w, 400, "Message is empty")
+		return
+	}
+	if chirptext.Length(msgReq.Body) > maxMessageLength {
+		respondWithError(w, 400, "Message is too long")
+		return
+	}
+	if !conv.IsGroup {
+		participants, err := cfg.dbQueries.ListConversationParticipants(req.Context(), conv.ID)
+		if err != nil {
+			respondWithError(w, 500, err.Error())
+			return
+		}
+		for _, participantID := range participants {
+			if participantID == userID {
+				continue
+			}
+			blocked, err := cfg.dbQueries.IsBlockedBetween(req.Context(), database.IsBlockedBetweenParams{UserA: userID, UserB: participantID})
+			if err != nil {
+				respondWithError(w, 500, err.Error())
+				return
+			}
+			if blocked {
+				respondWithError(w, 403, "You can't message this user")
+				return
+			}
+		}
+	}
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	m, err := qtx.CreateMessage(req.Context(), database.CreateMessageParams{ConversationID: conv.ID, SenderID: userID, Body: msgReq.Body})
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	if err = qtx.TouchConversation(req.Context(), conv.ID); err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	err = qtx.MarkConversationRead(req.Context(), database.MarkConversationReadParams{ConversationID: conv.ID, UserID: userID, ReadAt: m.CreatedAt})
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	if err = tx.Commit(); err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	respondWithJSON(w, 201, Message(m))
+}
+
+func (cfg *apiConfig) handlerMessagesList(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	conv, ok := cfg.participantConversation(w, req, userID)
+	if !ok {
+		return
+	}
+	limit, err := pagination.Limit(req.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	params := database.ListMessagesParams{ConversationID: conv.ID, MaxResults: int32(limit)}
+	if c := req.URL.Query().Get("cursor"); c != "" {
+		cursor, err := pagination.Decode(c)
+		if err != nil {
+			respondWithError(w, 400, err.Error())
+			return
+		}
+		params.BeforeCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	dbMessages, err := cfg.dbQueries.ListMessages(req.Context(), params)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	blocks, err := cfg.dbQueries.ListBlocks(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	page := MessagePage{Messages: []Message{}}
+	for _, m := range dbMessages {
+		if slices.ContainsFunc(blocks, func(b database.Block) bool { return b.BlockedID == m.SenderID }) {
+			continue
+		}
+		page.Messages = append(page.Messages, Message(m))
+	}
+	if len(dbMessages) == limit {
+		last := dbMessages[len(dbMessages)-1]
+		page.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	respondWithJSON(w, 200, page)
+}
+
+func (cfg *apiConfig) handlerConversationRead(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	conv, ok := cfg.participantConversation(w, req, userID)
+	if !ok {
+		return
+	}
+	marker := ReadMarkerRequest{}
+	if req.ContentLength != 0 {
+		decoder := json.NewDecoder(req.Body)
+		if err := decoder.Decode(&marker); err != nil {
+			respondWithError(w, 400, "Error decoding request body")
+			return
+		}
+	}
+	readAt := time.Now().UTC()
+	if marker.MessageID != uuid.Nil {
+		m, err := cfg.dbQueries.GetMessage(req.Context(), database.GetMessageParams{ID: marker.MessageID, ConversationID: conv.ID})
+		if err != nil {
+			respondWithError(w, 404, "Message not found")
+			return
+		}
+		readAt = m.CreatedAt
+	}
+	err := cfg.dbQueries.MarkConversationRead(req.Context(), database.MarkConversationReadParams{ConversationID: conv.ID, UserID: userID, ReadAt: readAt})
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
+
+// participantConversation loads the conversation named in the path, responding
+// with 404 unless the user takes part in it.
+func (cfg *apiConfig) participantConversation(w http.ResponseWriter, req *http.Request, userID uuid.UUID) (database.GetConversationForParticipantRow, bool) {
+	conversationID, err := uuid.Parse(req.PathValue("conversationID"))
+	if err != nil {
+		respondWithError(w, 404, "Conversation not found")
+		return database.GetConversationForParticipantRow{}, false
+	}
+	conv, err := cfg.dbQueries.GetConversationForParticipant(req.Context(), database.GetConversationForParticipantParams{ID: conversationID, UserID: userID})
+	if err != nil {
+		respondWithError(w, 404, "Conversation not found")
+		return database.GetConversationForParticipantRow{}, false
+	}
+	return conv, true
+}
+
+func (cfg *apiConfig) conversationForUser(req *http.Request, conversationID, userID uuid.UUID) (Conversation, error) {
+	conv, err := cfg.dbQueries.GetConversationForParticipant(req.Context(), database.GetConversationForParticipantParams{ID: conversationID, UserID: userID})
+	if err != nil {
+		return Conversation{}, err
+	}
+	participants, err := cfg.dbQueries.ListConversationParticipants(req.Context(), conversationID)
+	if err != nil {
+		return Conversation{}, err
+	}
+	return Conversation{
+		ID:             conv.ID,
+		CreatedAt:      conv.CreatedAt,
+		UpdatedAt:      conv.UpdatedAt,
+		IsGroup:        conv.IsGroup,
+		ParticipantIDs: participants,
+		LastReadAt:     nullTimePtr(conv.LastReadAt),
+	}, nil
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/jobs"
+	"chirpy/internal/visibility"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxDraftLength    = 10000
+	schedulerInterval = 10 * time.Second
+	// maxDraftAttempts is how many times publishing a scheduled draft fails
+	// with an unexpected error before it's unscheduled.
+	maxDraftAttempts = 5
+)
+
+type Draft struct {
+	ID           uuid.UUID    `json:"id"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+	Body         string       `json:"body"`
+	MediaIDs     []uuid.UUID  `json:"media_ids"`
+	Poll         *PollRequest `json:"poll"`
+	Visibility   string       `json:"visibility"`
+	PublishAt    *time.Time   `json:"publish_at"`
+	PublishError *string      `json:"publish_error"`
+}
+
+func draftFromDB(d database.Draft) Draft {
+	draft := Draft{
+		ID:         d.ID,
+		CreatedAt:  d.CreatedAt,
+		UpdatedAt:  d.UpdatedAt,
+		Body:       d.Body,
+		MediaIDs:   d.MediaIds,
+		Visibility: d.Visibility,
+		PublishAt:  nullTimePtr(d.PublishAt),
+	}
+	if d.PublishError.Valid {
+		draft.PublishError = &d.PublishError.String
+	}
+	if draft.MediaIDs == nil {
+		draft.MediaIDs = []uuid.UUID{}
+	}
+	if d.PollExpiresAt.Valid {
+		draft.Poll = &PollRequest{Options: d.PollOptions, ExpiresAt: d.PollExpiresAt.Time}
+	}
+	return draft
+}
+
+// chirpRequest turns a draft back into the request that publishes it.
+func (d Draft) chirpRequest() ChirpRequest {
+	return ChirpRequest{Body: d.Body, MediaIDs: d.MediaIDs, Poll: d.Poll, Visibility: d.Visibility}
+}
+
+// draftParams converts a chirp request to the columns stored for a draft.
+// Arrays are never nil since the columns are NOT NULL.
+func draftParams(chirpReq ChirpRequest) (mediaIDs []uuid.UUID, pollOptions []string, pollExpiresAt, publishAt sql.NullTime) {
+	mediaIDs = append([]uuid.UUID{}, chirpReq.MediaIDs...)
+	pollOptions = []string{}
+	if chirpReq.Poll != nil {
+		pollOptions = append(pollOptions, chirpReq.Poll.Options...)
+		pollExpiresAt = sql.NullTime{Time: chirpReq.Poll.ExpiresAt.UTC(), Valid: true}
+	}
+	if chirpReq.PublishAt != nil {
+		publishAt = sql.NullTime{Time: chirpReq.PublishAt.UTC(), Valid: true}
+	}
+	return mediaIDs, pollOptions, pollExpiresAt, publishAt
+}
+
+// checkDraft validates a draft about to be saved. Unscheduled drafts are work
+// in progress and only checked when published; scheduled ones must be valid
+// as of their publish time.
+func (cfg *apiConfig) checkDraft(ctx context.Context, userID uuid.UUID, chirpReq ChirpRequest) error {
+	if len(chirpReq.Body) > maxDraftLength {
+		return &requestError{400, "Draft is too long"}
+	}
+	if chirpReq.Visibility != "" && !visibility.Valid(chirpReq.Visibility) {
+		return &requestError{400, "Invalid visibility"}
+	}
+	if chirpReq.PublishAt == nil {
+		return nil
+	}
+	if !chirpReq.PublishAt.After(time.Now()) {
+		return &requestError{400, "publish_at must be in the future"}
+	}
+	return cfg.validateChirp(ctx, userID, chirpReq, *chirpReq.PublishAt)
+}
+
+// scheduleChirp stores a chirp sent with publish_at as a scheduled draft.
+func (cfg *apiConfig) scheduleChirp(w http.ResponseWriter, req *http.Request, userID uuid.UUID, chirpReq ChirpRequest) {
+	if err := cfg.checkDraft(req.Context(), userID, chirpReq); err != nil {
+		respondWithRequestError(w, err)
+		return
+	}
+	mediaIDs, pollOptions, pollExpiresAt, publishAt := draftParams(chirpReq)
+	d, err := cfg.dbQueries.CreateDraft(req.Context(), database.CreateDraftParams{
+		UserID:        userID,
+		Body:          chirpReq.Body,
+		MediaIds:      mediaIDs,
+		PollOptions:   pollOptions,
+		PollExpiresAt: pollExpiresAt,
+		PublishAt:     publishAt,
+		Visibility:    chirpReq.visibility(),
+	})
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	respondWithJSON(w, 202, draftFromDB(d))
+}
+
+func (cfg *apiConfig) handlerDraftsCreate(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	draftReq := ChirpRequest{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&draftReq); err != nil {
+		respondWithError(w, 400, "Error decoding draft")
+		return
+	}
+	if draftReq.PublishAt != nil && !cfg.rateLimit(w, req, userID) {
+		return
+	}
+	if err := cfg.checkDraft(req.Context(), userID, draftReq); err != nil {
+		respondWithRequestError(w, err)
+		return
+	}
+	mediaIDs, pollOptions, pollExpiresAt, publishAt := draftParams(draftReq)
+	d, err := cfg.dbQueries.CreateDraft(req.Context(), database.CreateDraftParams{
+		UserID:        userID,
+		Body:          draftReq.Body,
+		MediaIds:      mediaIDs,
+		PollOptions:   pollOptions,
+		PollExpiresAt: pollExpiresAt,
+		PublishAt:     publishAt,
+		Visibility:    draftReq.visibility(),
+	})
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	respondWithJSON(w, 201, draftFromDB(d))
+}
+
+func (cfg *apiConfig) handlerDraftsList(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	dbDrafts, err := cfg.dbQueries.ListDrafts(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	drafts := make([]Draft, len(dbDrafts))
+	for i, d := range dbDrafts {
+		drafts[i] = draftFromDB(d)
+	}
+	respondWithJSON(w, 200, drafts)
+}
+
+func (cfg *apiConfig) handlerDraftsGet(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	draftID, err := uuid.Parse(req.PathValue("draftID"))
+	if err != nil {
+		respondWithError(w, 404, "Draft not found")
+		return
+	}
+	d, err := cfg.dbQueries.GetDraft(req.Context(), database.GetDraftParams{ID: draftID, UserID: userID})
+	if err != nil {
+		respondWithError(w, 404, "Draft not found")
+		return
+	}
+	respondWithJSON(w, 200, draftFromDB(d))
+}
+
+// handlerDraftsUpdate replaces a draft. Sending publish_at schedules it and
+// leaving it out unschedules it. Saving a scheduled draft counts against the
+// author's chirp rate limit, as the scheduler publishes it unchecked.
+func (cfg *apiConfig) handlerDraftsUpdate(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	draftID, err := uuid.Parse(req.PathValue("draftID"))
+	if err != nil {
+		respondWithError(w, 404, "Draft not found")
+		return
+	}
+	draftReq := ChirpRequest{}
+	decoder := json.NewDecoder(req.Body)
+	if err = decoder.Decode(&draftReq); err != nil {
+		respondWithError(w, 400, "Error decoding draft")
+		return
+	}
+	if draftReq.PublishAt != nil && !cfg.rateLimit(w, req, userID) {
+		return
+	}
+	if err = cfg.checkDraft(req.Context(), userID, draftReq); err != nil {
+		respondWithRequestError(w, err)
+		return
+	}
+	mediaIDs, pollOptions, pollExpiresAt, publishAt := draftParams(draftReq)
+	d, err := cfg.dbQueries.UpdateDraft(req.Context(), database.UpdateDraftParams{
+		ID:            draftID,
+		UserID:        userID,
+		Body:          draftReq.Body,
+		MediaIds:      mediaIDs,
+		PollOptions:   pollOptions,
+		PollExpiresAt: pollExpiresAt,
+		PublishAt:     publishAt,
+		Visibility:    draftReq.visibility(),
+	})
+	if err != nil {
+		respondWithError(w, 404, "Draft not found")
+		return
+	}
+	respondWithJSON(w, 200, draftFromDB(d))
+}
+
+func (cfg *apiConfig) handlerDraftsDelete(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	draftID, err := uuid.Parse(req.PathValue("draftID"))
+	if err != nil {
+		respondWithError(w, 404, "Draft not found")
+		return
+	}
+	deleted, err := cfg.dbQueries.DeleteDraft(req.Context(), database.DeleteDraftParams{ID: draftID, UserID: userID})
+	if err != nil {
+		respondWithError(w, 500, "Error deleting draft")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "Draft not found")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
+
+// handlerDraftsPublish publishes a draft right away, whether or not it's
+// scheduled.
+func (cfg *apiConfig) handlerDraftsPublish(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	if !cfg.rateLimit(w, req, userID) {
+		return
+	}
+	draftID, err := uuid.Parse(req.PathValue("draftID"))
+	if err != nil {
+		respondWithError(w, 404, "Draft not found")
+		return
+	}
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	// Holding the row lock keeps the scheduler from publishing it too.
+	d, err := qtx.LockDraft(req.Context(), database.LockDraftParams{ID: draftID, UserID: userID})
+	if err != nil {
+		respondWithError(w, 404, "Draft not found")
+		return
+	}
+	c, err := cfg.publishDraft(req.Context(), qtx, d)
+	if err != nil {
+		respondWithRequestError(w, err)
+		return
+	}
+	if err = tx.Commit(); err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	cfg.metrics.ChirpsCreated.Inc()
+	chirps, err := cfg.renderChirps(req.Context(), userID, []database.Chirp{c})
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	respondWithJSON(w, 201, chirps[0])
+}
+
+// retryScheduledDraft records a failed attempt at publishing d, unscheduling
+// it once it has failed maxDraftAttempts times.
+func (cfg *apiConfig) retryScheduledDraft(ctx context.Context, d database.Draft, publishErr error) error {
+	log.Printf("Error publishing scheduled draft %s: %s", d.ID, publishErr)
+	message := sql.NullString{String: "Publishing failed, will retry", Valid: true}
+	attempts, err := cfg.dbQueries.RetryScheduledDraft(ctx, database.RetryScheduledDraftParams{
+		ID:            d.ID,
+		PublishError:  message,
+		NextAttemptAt: time.Now().UTC().Add(jobs.Backoff(int(d.PublishAttempts) + 1)),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// Deleted, or published by another instance, since.
+		return nil
+	}
+	if err != nil || attempts < maxDraftAttempts {
+		return err
+	}
+	return cfg.dbQueries.FailScheduledDraft(ctx, database.FailScheduledDraftParams{
+		ID:           d.ID,
+		PublishError: sql.NullString{String: "Publishing failed", Valid: true},
+	})
+}
+
+// publishDraft turns a locked draft into a chirp and deletes it. q must be
+// bound to the transaction holding the lock.
+func (cfg *apiConfig) publishDraft(ctx context.Context, q *database.Queries, d database.Draft) (database.Chirp, error) {
+	chirpReq := draftFromDB(d).chirpRequest()
+	if err := cfg.validateChirp(ctx, d.UserID, chirpReq, validAsOf(d, time.Now())); err != nil {
+		return database.Chirp{}, err
+	}
+	c, err := insertChirp(ctx, q, d.UserID, chirpReq)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if _, err = q.DeleteDraft(ctx, database.DeleteDraftParams{ID: d.ID, UserID: d.UserID}); err != nil {
+		return database.Chirp{}, err
+	}
+	return c, nil
+}
+
+// validAsOf returns the time a draft being published at now is validated
+// as of. Scheduled drafts were checked against their publish time when they
+// were saved, so a scheduler running late mustn't fail a poll that was only
+// valid from then; drafts published early are checked as of now.
+func validAsOf(d database.Draft, now time.Time) time.Time {
+	if d.PublishAt.Valid && d.PublishAt.Time.Before(now) {
+		return d.PublishAt.Time
+	}
+	return now
+}
+
+// runScheduler publishes scheduled drafts once they're due, until ctx is
+// done. Drafts are claimed with FOR UPDATE SKIP LOCKED and deleted in the
+// same transaction that creates the chirp, so every instance can run a
+// scheduler without publishing anything twice, and nothing is lost across
+// restarts.
+func (cfg *apiConfig) runScheduler(ctx context.Context) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+	for {
+		for ctx.Err() == nil {
+			cfg.heartbeats.Beat("scheduler", schedulerInterval, time.Now())
+			published, err := cfg.publishDueDraft(context.WithoutCancel(ctx))
+			if err != nil {
+				log.Printf("Error publishing scheduled chirp: %s", err)
+				break
+			}
+			if !published {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishDueDraft publishes one due draft and reports whether there was one.
+// Drafts that are no longer valid, say because their media got attached to
+// another chirp, are unscheduled with the reason kept in publish_error. Other
+// failures are retried with backoff, so one draft that keeps failing doesn't
+// hold up the ones behind it.
+func (cfg *apiConfig) publishDueDraft(ctx context.Context) (bool, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	d, err := qtx.ClaimDueDraft(ctx, time.Now().UTC())
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	_, err = cfg.publishDraft(ctx, qtx, d)
+	if reqErr := new(requestError); errors.As(err, &reqErr) {
+		// The failed insert may have aborted the transaction, so record
+		// the failure in a fresh one.
+		tx.Rollback()
+		err = cfg.dbQueries.FailScheduledDraft(ctx, database.FailScheduledDraftParams{
+			ID:           d.ID,
+			PublishError: sql.NullString{String: reqErr.message, Valid: true},
+		})
+		return err == nil, err
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		tx.Rollback()
+		return true, cfg.retryScheduledDraft(ctx, d, err)
+	}
+	cfg.metrics.ChirpsCreated.Inc()
+	return true, nil
+}
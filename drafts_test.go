@@ -0,0 +1,48 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/polls"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestValidAsOf(t *testing.T) {
+	publishAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	scheduled := database.Draft{PublishAt: sql.NullTime{Time: publishAt, Valid: true}}
+	tests := []struct {
+		name string
+		d    database.Draft
+		now  time.Time
+		want time.Time
+	}{
+		{name: "Unscheduled", d: database.Draft{}, now: publishAt, want: publishAt},
+		{name: "Scheduler on time", d: scheduled, now: publishAt, want: publishAt},
+		{name: "Scheduler running late", d: scheduled, now: publishAt.Add(10 * time.Second), want: publishAt},
+		{name: "Published early", d: scheduled, now: publishAt.Add(-time.Hour), want: publishAt.Add(-time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validAsOf(tt.d, tt.now); !got.Equal(tt.want) {
+				t.Errorf("validAsOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// A poll scheduled to run for exactly polls.MinDuration was valid when it
+// was accepted, and must stay valid when the scheduler gets to it late.
+func TestValidAsOfShortestPoll(t *testing.T) {
+	publishAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	d := database.Draft{PublishAt: sql.NullTime{Time: publishAt, Valid: true}}
+	options := []string{"yes", "no"}
+	expiresAt := publishAt.Add(polls.MinDuration)
+	if err := polls.Validate(options, expiresAt, publishAt); err != nil {
+		t.Fatalf("Validate() at scheduling error = %v", err)
+	}
+	if err := polls.Validate(options, expiresAt, validAsOf(d, publishAt.Add(10*time.Second))); err != nil {
+		t.Errorf("Validate() when published late error = %v", err)
+	}
+}
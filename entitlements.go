@@ -0,0 +1,100 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/entitlements"
+	"chirpy/internal/subscriptions"
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Entitlements struct {
+	Plan              string `json:"plan"`
+	MaxChirpLength    int    `json:"max_chirp_length"`
+	MaxPins           int    `json:"max_pins"`
+	MaxMedia          int    `json:"max_media"`
+	RateLimit         int    `json:"rate_limit_per_minute"`
+	EditWindowSeconds int    `json:"edit_window_seconds"`
+}
+
+// userPlan returns the plan that sets user's capabilities: their
+// subscription's while they're Chirpy Red, the free plan otherwise.
+func (cfg *apiConfig) userPlan(ctx context.Context, user database.User) (string, error) {
+	if !user.IsChirpyRed {
+		return entitlements.PlanFree, nil
+	}
+	sub, err := cfg.dbQueries.GetSubscription(ctx, user.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return subscriptions.DefaultPlan, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return sub.Plan, nil
+}
+
+// capabilities returns the limits that apply to user.
+func (cfg *apiConfig) capabilities(ctx context.Context, user database.User) (entitlements.Capabilities, error) {
+	plan, err := cfg.userPlan(ctx, user)
+	if err != nil {
+		return entitlements.Capabilities{}, err
+	}
+	return cfg.entitlements.For(plan, user.IsChirpyRed), nil
+}
+
+// rateLimit counts a chirp or upload against userID's per-minute limit,
+// responding with 429 once it's used up.
+func (cfg *apiConfig) rateLimit(w http.ResponseWriter, req *http.Request, userID uuid.UUID) bool {
+	user, err := cfg.dbQueries.GetUserByID(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 401, "User not found")
+		return false
+	}
+	caps, err := cfg.capabilities(req.Context(), user)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return false
+	}
+	allowed, wait := cfg.limiter.Allow(userID.String(), caps.RateLimit, time.Now())
+	if !allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		respondWithError(w, 429, "Too many requests, try again later")
+		return false
+	}
+	return true
+}
+
+// handlerEntitlementsGet returns the user's plan and the limits that come
+// with it.
+func (cfg *apiConfig) handlerEntitlementsGet(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	user, err := cfg.dbQueries.GetUserByID(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 401, "User not found")
+		return
+	}
+	plan, err := cfg.userPlan(req.Context(), user)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	caps := cfg.entitlements.For(plan, user.IsChirpyRed)
+	respondWithJSON(w, 200, Entitlements{
+		Plan:              plan,
+		MaxChirpLength:    caps.MaxChirpLength,
+		MaxPins:           caps.MaxPins,
+		MaxMedia:          caps.MaxMedia,
+		RateLimit:         caps.RateLimit,
+		EditWindowSeconds: caps.EditWindowSeconds,
+	})
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"chirpy/internal/health"
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"time"
+)
+
+const readyzTimeout = 2 * time.Second
+
+//go:embed sql/schema/*.sql
+var migrations embed.FS
+
+// goose records each migration it applies or rolls back. The schema is at
+// the highest version whose latest record is an apply.
+const schemaVersionQuery = `
+SELECT COALESCE(MAX(version_id), 0) FROM (
+	SELECT DISTINCT ON (version_id) version_id, is_applied
+	FROM goose_db_version
+	ORDER BY version_id, id DESC
+) latest
+WHERE is_applied`
+
+// expectedSchemaVersion is the version of the newest migration built into
+// the binary.
+func expectedSchemaVersion() (int64, error) {
+	schema, err := fs.Sub(migrations, "sql/schema")
+	if err != nil {
+		return 0, err
+	}
+	return health.SchemaVersion(schema)
+}
+
+// handlerLivez reports that the process is up and serving. It doesn't check
+// dependencies, so an outage of the database doesn't get the server
+// restarted.
+func (cfg *apiConfig) handlerLivez(w http.ResponseWriter, req *http.Request) {
+	respondWithJSON(w, 200, health.Report{Status: health.StatusOK, Checks: []health.Result{}})
+}
+
+// handlerReadyz checks the dependencies needed to serve traffic: the
+// database must answer and its schema must be at least as new as the
+// binary's migrations. Stale background workers are reported but only
+// degrade the status, since requests can still be served.
+func (cfg *apiConfig) handlerReadyz(w http.ResponseWriter, req *http.Request) {
+	report := health.Run(req.Context(), readyzTimeout, []health.Check{
+		{Name: "database", Critical: true, Run: cfg.db.PingContext},
+		{Name: "migrations", Critical: true, Run: cfg.checkSchemaVersion},
+		{Name: "workers", Run: func(context.Context) error { return cfg.heartbeats.Check(time.Now()) }},
+	})
+	status := 200
+	if !report.Ready() {
+		status = 503
+	}
+	respondWithJSON(w, status, report)
+}
+
+// checkSchemaVersion fails if migrations the binary expects haven't been
+// applied. A newer schema is fine, as happens while a deploy rolls out.
+func (cfg *apiConfig) checkSchemaVersion(ctx context.Context) error {
+	var version int64
+	if err := cfg.db.QueryRowContext(ctx, schemaVersionQuery).Scan(&version); err != nil {
+		return err
+	}
+	if version < cfg.schemaVersion {
+		return fmt.Errorf("schema is at version %d, want %d", version, cfg.schemaVersion)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"log"
+	"time"
+)
+
+const historyGCInterval = time.Hour
+
+// historyTable deletes up to batchSize finished rows of one table last
+// touched before cutoff.
+type historyTable struct {
+	name   string
+	delete func(ctx context.Context, cutoff time.Time, batchSize int32) (int64, error)
+}
+
+// historyTables are the tables that keep finished work around for a while,
+// for admins and for clients resuming streams, but would grow forever if
+// it were never removed.
+func (cfg *apiConfig) historyTables() []historyTable {
+	q := cfg.dbQueries
+	return []historyTable{
+		{"jobs", func(ctx context.Context, cutoff time.Time, batchSize int32) (int64, error) {
+			return q.DeleteFinishedJobs(ctx, database.DeleteFinishedJobsParams{Cutoff: cutoff, BatchSize: batchSize})
+		}},
+		{"webhook_deliveries", func(ctx context.Context, cutoff time.Time, batchSize int32) (int64, error) {
+			return q.DeleteFinishedWebhookDeliveries(ctx, database.DeleteFinishedWebhookDeliveriesParams{Cutoff: cutoff, BatchSize: batchSize})
+		}},
+		{"webhook_events", func(ctx context.Context, cutoff time.Time, batchSize int32) (int64, error) {
+			return q.DeleteFinishedWebhookEvents(ctx, database.DeleteFinishedWebhookEventsParams{Cutoff: cutoff, BatchSize: batchSize})
+		}},
+		{"outbox", func(ctx context.Context, cutoff time.Time, batchSize int32) (int64, error) {
+			return q.DeleteFinishedOutbox(ctx, database.DeleteFinishedOutboxParams{Cutoff: cutoff, BatchSize: batchSize})
+		}},
+		{"chirp_events", func(ctx context.Context, cutoff time.Time, batchSize int32) (int64, error) {
+			return q.DeleteOldChirpEvents(ctx, database.DeleteOldChirpEventsParams{Cutoff: cutoff, BatchSize: batchSize})
+		}},
+	}
+}
+
+// collectHistory deletes finished jobs, webhook deliveries and events,
+// outbox events and chirp events older than historyRetention,
+// historyGCBatch rows at a time. Incoming webhook events are what retries
+// are deduplicated against, so the retention must be longer than providers
+// keep retrying.
+func (cfg *apiConfig) collectHistory(ctx context.Context) error {
+	cutoff := time.Now().UTC().Add(-cfg.historyRetention)
+	for _, table := range cfg.historyTables() {
+		var total int64
+		for {
+			deleted, err := table.delete(ctx, cutoff, int32(cfg.historyGCBatch))
+			total += deleted
+			if err != nil {
+				return err
+			}
+			if deleted < int64(cfg.historyGCBatch) {
+				break
+			}
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+		}
+		if total > 0 {
+			log.Printf("Removed %d old rows from %s", total, table.name)
+		}
+	}
+	return nil
+}
+
+// collectHistoryJob is the job form of collectHistory.
+func (cfg *apiConfig) collectHistoryJob(ctx context.Context, _ struct{}) error {
+	return cfg.collectHistory(ctx)
+}
@@ -47,23 +47,34 @@ func MakeJWT(userID uuid.UUID, tokenSecret string) (string, error) {
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	userID, _, err := ValidateJWTWithExpiry(tokenString, tokenSecret)
+	return userID, err
+}
+
+// ValidateJWTWithExpiry is ValidateJWT for long-lived connections that need to
+// know when the token stops being valid.
+func ValidateJWTWithExpiry(tokenString, tokenSecret string) (uuid.UUID, time.Time, error) {
 	claims := jwt.RegisteredClaims{}
 	token, err := jwt.NewParser().ParseWithClaims(tokenString, &claims, func(tkn *jwt.Token) (any, error) {
 		return []byte(tokenSecret), nil
 	})
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, time.Time{}, err
 	}
 	token.Claims = jwt.Claims(claims)
 	tokenSubject, err := token.Claims.GetSubject()
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, time.Time{}, err
 	}
 	userID, err := uuid.Parse(tokenSubject)
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, time.Time{}, err
+	}
+	expiresAt, err := token.Claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return uuid.Nil, time.Time{}, errors.New("token has no expiration time")
 	}
-	return userID, nil
+	return userID, expiresAt.Time, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
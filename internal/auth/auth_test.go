@@ -1,7 +1,10 @@
 package auth
 
 import (
+	"net/http"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -108,3 +111,150 @@ func TestValidateJWT(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateJWTWithExpiry(t *testing.T) {
+	userID := uuid.New()
+	before := time.Now()
+	token, _ := MakeJWT(userID, "secret")
+
+	gotUserID, expiresAt, err := ValidateJWTWithExpiry(token, "secret")
+	if err != nil {
+		t.Fatalf("ValidateJWTWithExpiry() error = %v", err)
+	}
+	if gotUserID != userID {
+		t.Errorf("ValidateJWTWithExpiry() gotUserID = %v, want %v", gotUserID, userID)
+	}
+	if expiresAt.Before(before.Add(time.Hour-time.Second)) || expiresAt.After(time.Now().Add(time.Hour)) {
+		t.Errorf("ValidateJWTWithExpiry() expiresAt = %v, want about an hour from now", expiresAt)
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"event":"user.upgraded","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c"}}`)
+	oldSecret := "old-secret"
+	newSecret := "new-secret"
+	headers := func(timestamp time.Time, signature string) http.Header {
+		h := http.Header{}
+		h.Set(TimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+		h.Set(SignatureHeader, signature)
+		return h
+	}
+
+	tests := []struct {
+		name    string
+		headers http.Header
+		body    []byte
+		secrets []string
+		wantErr error
+	}{
+		{
+			name:    "Valid signature",
+			headers: headers(now, SignWebhook(newSecret, now, body)),
+			body:    body,
+			secrets: []string{newSecret},
+			wantErr: nil,
+		},
+		{
+			name:    "Signed with a secret being rotated out",
+			headers: headers(now, SignWebhook(oldSecret, now, body)),
+			body:    body,
+			secrets: []string{newSecret, oldSecret},
+			wantErr: nil,
+		},
+		{
+			name:    "One of several signatures matches",
+			headers: headers(now, SignWebhook("unknown", now, body)+", "+SignWebhook(newSecret, now, body)),
+			body:    body,
+			secrets: []string{newSecret},
+			wantErr: nil,
+		},
+		{
+			name:    "Within tolerance",
+			headers: headers(now.Add(-4*time.Minute), SignWebhook(newSecret, now.Add(-4*time.Minute), body)),
+			body:    body,
+			secrets: []string{newSecret},
+			wantErr: nil,
+		},
+		{
+			name:    "Replayed after tolerance",
+			headers: headers(now.Add(-6*time.Minute), SignWebhook(newSecret, now.Add(-6*time.Minute), body)),
+			body:    body,
+			secrets: []string{newSecret},
+			wantErr: ErrSignatureExpired,
+		},
+		{
+			name:    "Tampered body",
+			headers: headers(now, SignWebhook(newSecret, now, body)),
+			body:    []byte(`{"event":"user.upgraded","data":{"user_id":"00000000-0000-0000-0000-000000000000"}}`),
+			secrets: []string{newSecret},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "Timestamp changed after signing",
+			headers: headers(now.Add(time.Second), SignWebhook(newSecret, now, body)),
+			body:    body,
+			secrets: []string{newSecret},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "Wrong secret",
+			headers: headers(now, SignWebhook(oldSecret, now, body)),
+			body:    body,
+			secrets: []string{newSecret},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "Missing headers",
+			headers: http.Header{},
+			body:    body,
+			secrets: []string{newSecret},
+			wantErr: ErrMissingSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWebhookSignature(tt.headers, tt.body, tt.secrets, 5*time.Minute, now)
+			if err != tt.wantErr {
+				t.Errorf("VerifyWebhookSignature() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompareAPIKey(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want string
+		ok   bool
+	}{
+		{
+			name: "Matching key",
+			key:  "f271c81ff7084ee5b99a5091b42d486e",
+			want: "f271c81ff7084ee5b99a5091b42d486e",
+			ok:   true,
+		},
+		{
+			name: "Different key",
+			key:  "f271c81ff7084ee5b99a5091b42d486f",
+			want: "f271c81ff7084ee5b99a5091b42d486e",
+			ok:   false,
+		},
+		{
+			name: "No key configured",
+			key:  "",
+			want: "",
+			ok:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CompareAPIKey(tt.key, tt.want); got != tt.ok {
+				t.Errorf("CompareAPIKey() = %v, want %v", got, tt.ok)
+			}
+		})
+	}
+}
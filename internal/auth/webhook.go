@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers carrying a webhook's signature and the Unix time it was signed at.
+const (
+	SignatureHeader = "X-Polka-Signature"
+	TimestampHeader = "X-Polka-Timestamp"
+)
+
+var (
+	ErrMissingSignature = errors.New("webhook signature or timestamp missing")
+	ErrSignatureExpired = errors.New("webhook timestamp outside of tolerance")
+	ErrInvalidSignature = errors.New("webhook signature doesn't match")
+)
+
+// CompareAPIKey reports whether key matches want, taking the same time
+// whatever the contents of key.
+func CompareAPIKey(key, want string) bool {
+	return want != "" && subtle.ConstantTimeCompare([]byte(key), []byte(want)) == 1
+}
+
+// SignWebhook returns the signature of body sent at timestamp: the hex
+// HMAC-SHA256 of "<unix timestamp>.<body>" keyed with secret, prefixed with
+// "sha256=".
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks the signature headers of a webhook against
+// its raw body. The timestamp must be within tolerance of now, which stops
+// captured requests from being replayed later. During a secret rotation the
+// signature header may list several comma-separated signatures, and any of
+// them matching any of the active secrets is accepted.
+func VerifyWebhookSignature(headers http.Header, body []byte, secrets []string, tolerance time.Duration, now time.Time) error {
+	signatures := headers.Get(SignatureHeader)
+	ts := headers.Get(TimestampHeader)
+	if signatures == "" || ts == "" {
+		return ErrMissingSignature
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrMissingSignature
+	}
+	timestamp := time.Unix(unix, 0)
+	if timestamp.Before(now.Add(-tolerance)) || timestamp.After(now.Add(tolerance)) {
+		return ErrSignatureExpired
+	}
+	valid := false
+	for _, secret := range secrets {
+		expected := []byte(SignWebhook(secret, timestamp, body))
+		for _, signature := range strings.Split(signatures, ",") {
+			// Keep going after a match so timing doesn't reveal which
+			// secret or signature matched.
+			if hmac.Equal([]byte(strings.TrimSpace(signature)), expected) {
+				valid = true
+			}
+		}
+	}
+	if !valid {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package chirptext
+
+import (
+	"regexp"
+
+	"github.com/rivo/uniseg"
+)
+
+const (
+	// URLWeight is the number of characters a URL of up to MaxURLLength
+	// bytes counts for, regardless of its actual length. Longer URLs count
+	// in full.
+	URLWeight    = 23
+	MaxURLLength = 512
+)
+
+var urlPattern = regexp.MustCompile(`https?://[^\s]+`)
+
+// Length returns the length of a chirp body as users perceive it: grapheme
+// clusters rather than bytes, with URLs counted as URLWeight.
+func Length(body string) int {
+	length := 0
+	last := 0
+	for _, loc := range urlPattern.FindAllStringIndex(body, -1) {
+		if loc[1]-loc[0] > MaxURLLength {
+			continue
+		}
+		length += uniseg.GraphemeClusterCount(body[last:loc[0]])
+		length += URLWeight
+		last = loc[1]
+	}
+	length += uniseg.GraphemeClusterCount(body[last:])
+	return length
+}
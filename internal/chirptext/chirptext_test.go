@@ -0,0 +1,68 @@
+package chirptext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLength(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{
+			name: "Empty body",
+			body: "",
+			want: 0,
+		},
+		{
+			name: "ASCII text",
+			body: "hello chirpy",
+			want: 12,
+		},
+		{
+			name: "Accented letters",
+			body: "zażółć gęślą jaźń",
+			want: 17,
+		},
+		{
+			name: "Combining marks",
+			body: "éé",
+			want: 2,
+		},
+		{
+			name: "Emoji with modifiers",
+			body: "hi 👍🏽 👨‍👩‍👧",
+			want: 6,
+		},
+		{
+			name: "Single URL",
+			body: "https://example.com/a/very/long/path?with=query&and=more",
+			want: URLWeight,
+		},
+		{
+			name: "URL surrounded by text",
+			body: "see http://a.io now",
+			want: 4 + URLWeight + 4,
+		},
+		{
+			name: "URL over the maximum length",
+			body: "https://a.io/" + strings.Repeat("x", MaxURLLength),
+			want: len("https://a.io/") + MaxURLLength,
+		},
+		{
+			name: "Long emoji chirp",
+			body: strings.Repeat("🐦", 140),
+			want: 140,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Length(tt.body); got != tt.want {
+				t.Errorf("Length() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is Chirpy's configuration. Each field is read from the environment
+// variable in its env tag. Failing that, it's read from the config file
+// under the same name in lower case, then from its default. For any
+// setting, NAME_FILE (or name_file in the config file) can instead give the
+// path of a file holding the value, which is how secrets are usually
+// mounted.
+type Config struct {
+	Port     int    `env:"PORT" default:"8080"`
+	Platform string `env:"PLATFORM"`
+
+	DBURL     string `env:"DB_URL" required:"true" secret:"true"`
+	JWTSecret string `env:"JWT_SECRET" required:"true" secret:"true"`
+
+	PolkaKey                     string   `env:"POLKA_KEY" secret:"true"`
+	PolkaWebhookSecrets          []string `env:"POLKA_WEBHOOK_SECRETS" secret:"true"`
+	PolkaWebhookToleranceSeconds int      `env:"POLKA_WEBHOOK_TOLERANCE_SECONDS" default:"300"`
+	AdminAPIKey                  string   `env:"ADMIN_API_KEY" secret:"true"`
+
+	EntitlementsFile string `env:"ENTITLEMENTS_FILE"`
+
+	MediaStorage      string `env:"MEDIA_STORAGE" default:"fs"`
+	MediaDir          string `env:"MEDIA_DIR" default:"media"`
+	S3Endpoint        string `env:"S3_ENDPOINT"`
+	S3Region          string `env:"S3_REGION"`
+	S3Bucket          string `env:"S3_BUCKET"`
+	S3AccessKeyID     string `env:"S3_ACCESS_KEY_ID"`
+	S3SecretAccessKey string `env:"S3_SECRET_ACCESS_KEY" secret:"true"`
+
+	JobWorkers     int `env:"JOB_WORKERS" default:"4"`
+	WebhookWorkers int `env:"WEBHOOK_WORKERS" default:"2"`
+
+	HTTPReadHeaderTimeoutSeconds int `env:"HTTP_READ_HEADER_TIMEOUT_SECONDS" default:"5"`
+	HTTPReadTimeoutSeconds       int `env:"HTTP_READ_TIMEOUT_SECONDS" default:"30"`
+	HTTPWriteTimeoutSeconds      int `env:"HTTP_WRITE_TIMEOUT_SECONDS" default:"60"`
+	HTTPIdleTimeoutSeconds       int `env:"HTTP_IDLE_TIMEOUT_SECONDS" default:"120"`
+	MaxBodyBytes                 int `env:"MAX_BODY_BYTES" default:"1048576"`
+	ShutdownTimeoutSeconds       int `env:"SHUTDOWN_TIMEOUT_SECONDS" default:"30"`
+
+	RefreshTokenRetentionDays int `env:"REFRESH_TOKEN_RETENTION_DAYS" default:"7"`
+	RefreshTokenGCBatch       int `env:"REFRESH_TOKEN_GC_BATCH" default:"1000"`
+	HistoryRetentionDays      int `env:"HISTORY_RETENTION_DAYS" default:"30"`
+	HistoryGCBatch            int `env:"HISTORY_GC_BATCH" default:"1000"`
+
+	OutboxSinks             []string `env:"OUTBOX_SINKS"`
+	OutboxWebhookURL        string   `env:"OUTBOX_WEBHOOK_URL"`
+	OutboxWebhookSecret     string   `env:"OUTBOX_WEBHOOK_SECRET" secret:"true"`
+	OutboxNATSURL           string   `env:"OUTBOX_NATS_URL"`
+	OutboxNATSSubjectPrefix string   `env:"OUTBOX_NATS_SUBJECT_PREFIX" default:"chirpy"`
+}
+
+// Source looks up a setting by its environment variable name.
+type Source func(name string) (string, bool)
+
+// Load reads the configuration from the environment and, if path isn't
+// empty, a YAML file, then validates it.
+func Load(path string) (Config, error) {
+	file := Source(func(string) (string, bool) { return "", false })
+	if path != "" {
+		var err error
+		if file, err = readFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	return load(os.LookupEnv, file)
+}
+
+// readFile reads a flat YAML file of settings. Lists may be given as YAML
+// sequences or comma-separated strings.
+func readFile(path string) (Source, error) {
+	ext := filepath.Ext(path)
+	if ext != ".yaml" && ext != ".yml" {
+		return nil, fmt.Errorf("config file %s: only YAML files are supported", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	raw := map[string]any{}
+	if err = yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	settings := map[string]string{}
+	for key, value := range raw {
+		switch v := value.(type) {
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			settings[key] = strings.Join(items, ",")
+		case map[string]any:
+			return nil, fmt.Errorf("config file %s: %s must be a value or a list", path, key)
+		case nil:
+		default:
+			settings[key] = fmt.Sprint(v)
+		}
+	}
+	return func(name string) (string, bool) {
+		value, ok := settings[strings.ToLower(name)]
+		return value, ok
+	}, nil
+}
+
+func load(env, file Source) (Config, error) {
+	config := Config{}
+	errs := []error{}
+	v := reflect.ValueOf(&config).Elem()
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name := field.Tag.Get("env")
+		value, ok, err := lookup(name, env, file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !ok {
+			value, ok = field.Tag.Lookup("default")
+		}
+		if !ok || value == "" {
+			if field.Tag.Get("required") == "true" {
+				errs = append(errs, fmt.Errorf("%s is required", name))
+			}
+			if !ok {
+				continue
+			}
+		}
+		if err = set(v.Field(i), value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return Config{}, err
+	}
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// lookup finds a setting in the environment, then the file. At each level
+// the value itself wins over NAME_FILE.
+func lookup(name string, sources ...Source) (string, bool, error) {
+	for _, source := range sources {
+		if value, ok := source(name); ok {
+			return value, true, nil
+		}
+		if path, ok := source(name + "_FILE"); ok {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", false, fmt.Errorf("%s_FILE: %w", name, err)
+			}
+			return strings.TrimRight(string(data), "\r\n"), true, nil
+		}
+	}
+	return "", false, nil
+}
+
+func set(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(strings.TrimSpace(value))
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%q isn't a whole number", value)
+		}
+		field.SetInt(int64(n))
+	case reflect.Slice:
+		list := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		field.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Kind())
+	}
+	return nil
+}
+
+// Validate checks the settings make sense together.
+func (c Config) Validate() error {
+	errs := []error{}
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT must be between 1 and 65535"))
+	}
+	v := reflect.ValueOf(c)
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		if field.Type.Kind() == reflect.Int && v.Field(i).Int() <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", field.Tag.Get("env")))
+		}
+	}
+	// Signed Polka events older than the tolerance are refused before they
+	// reach deduplication, so only processed events younger than that need
+	// to be kept to stop replays.
+	if c.HistoryRetentionDays*24*60*60 <= c.PolkaWebhookToleranceSeconds {
+		errs = append(errs, errors.New("HISTORY_RETENTION_DAYS must be longer than POLKA_WEBHOOK_TOLERANCE_SECONDS"))
+	}
+	switch c.MediaStorage {
+	case "fs":
+	case "s3":
+		if c.S3Bucket == "" {
+			errs = append(errs, errors.New("S3_BUCKET is required when MEDIA_STORAGE is s3"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("MEDIA_STORAGE must be fs or s3, not %q", c.MediaStorage))
+	}
+	for _, sink := range c.OutboxSinks {
+		if !slices.Contains([]string{"log", "webhook", "nats"}, sink) {
+			errs = append(errs, fmt.Errorf("unknown outbox sink %q", sink))
+		}
+	}
+	if slices.Contains(c.OutboxSinks, "webhook") && c.OutboxWebhookURL == "" {
+		errs = append(errs, errors.New("OUTBOX_WEBHOOK_URL is required for the webhook outbox sink"))
+	}
+	if slices.Contains(c.OutboxSinks, "nats") && c.OutboxNATSURL == "" {
+		errs = append(errs, errors.New("OUTBOX_NATS_URL is required for the nats outbox sink"))
+	}
+	return errors.Join(errs...)
+}
+
+// Redacted lists the effective settings, one NAME=value per line, with
+// secrets that are set masked.
+func (c Config) Redacted() string {
+	var b strings.Builder
+	v := reflect.ValueOf(c)
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		var value string
+		switch f := v.Field(i); f.Kind() {
+		case reflect.Slice:
+			value = strings.Join(f.Interface().([]string), ",")
+		default:
+			value = fmt.Sprint(f.Interface())
+		}
+		if value != "" && field.Tag.Get("secret") == "true" {
+			value = "[redacted]"
+		}
+		fmt.Fprintf(&b, "%s=%s\n", field.Tag.Get("env"), value)
+	}
+	return b.String()
+}
+
+// Addr is the address the server listens on.
+func (c Config) Addr() string {
+	return ":" + strconv.Itoa(c.Port)
+}
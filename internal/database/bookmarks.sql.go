@@ -0,0 +1,294 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: bookmarks.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createBookmark = `-- name: CreateBookmark :one
+INSERT INTO bookmarks (id, created_at, user_id, chirp_id, collection_id)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3)
+ON CONFLICT (user_id, chirp_id) DO UPDATE SET collection_id=EXCLUDED.collection_id
+RETURNING id, created_at, user_id, chirp_id, collection_id
+`
+
+type CreateBookmarkParams struct {
+	UserID       uuid.UUID
+	ChirpID      uuid.UUID
+	CollectionID uuid.NullUUID
+}
+
+func (q *Queries) CreateBookmark(ctx context.Context, arg CreateBookmarkParams) (Bookmark, error) {
+	row := q.db.QueryRowContext(ctx, createBookmark, arg.UserID, arg.ChirpID, arg.CollectionID)
+	var i Bookmark
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.ChirpID,
+		&i.CollectionID,
+	)
+	return i, err
+}
+
+const createBookmarkCollection = `-- name: CreateBookmarkCollection :one
+INSERT INTO bookmark_collections (id, created_at, updated_at, user_id, name)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
+RETURNING id, created_at, updated_at, user_id, name
+`
+
+type CreateBookmarkCollectionParams struct {
+	UserID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) CreateBookmarkCollection(ctx context.Context, arg CreateBookmarkCollectionParams) (BookmarkCollection, error) {
+	row := q.db.QueryRowContext(ctx, createBookmarkCollection, arg.UserID, arg.Name)
+	var i BookmarkCollection
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+	)
+	return i, err
+}
+
+const deleteBookmark = `-- name: DeleteBookmark :execrows
+DELETE FROM bookmarks WHERE user_id=$1 AND chirp_id=$2
+`
+
+type DeleteBookmarkParams struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) DeleteBookmark(ctx context.Context, arg DeleteBookmarkParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteBookmark, arg.UserID, arg.ChirpID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteBookmarkCollection = `-- name: DeleteBookmarkCollection :execrows
+DELETE FROM bookmark_collections WHERE id=$1 AND user_id=$2
+`
+
+type DeleteBookmarkCollectionParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteBookmarkCollection(ctx context.Context, arg DeleteBookmarkCollectionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteBookmarkCollection, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getBookmarkCollection = `-- name: GetBookmarkCollection :one
+SELECT id, created_at, updated_at, user_id, name FROM bookmark_collections WHERE id=$1 AND user_id=$2
+`
+
+type GetBookmarkCollectionParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetBookmarkCollection(ctx context.Context, arg GetBookmarkCollectionParams) (BookmarkCollection, error) {
+	row := q.db.QueryRowContext(ctx, getBookmarkCollection, arg.ID, arg.UserID)
+	var i BookmarkCollection
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+	)
+	return i, err
+}
+
+const listBookmarkCollections = `-- name: ListBookmarkCollections :many
+SELECT bookmark_collections.id, bookmark_collections.created_at, bookmark_collections.updated_at, bookmark_collections.user_id, bookmark_collections.name, COUNT(bookmarks.id) AS bookmark_count
+FROM bookmark_collections
+LEFT JOIN bookmarks ON bookmarks.collection_id=bookmark_collections.id
+WHERE bookmark_collections.user_id=$1
+GROUP BY bookmark_collections.id
+ORDER BY bookmark_collections.name
+`
+
+type ListBookmarkCollectionsRow struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	UserID        uuid.UUID
+	Name          string
+	BookmarkCount int64
+}
+
+func (q *Queries) ListBookmarkCollections(ctx context.Context, userID uuid.UUID) ([]ListBookmarkCollectionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listBookmarkCollections, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListBookmarkCollectionsRow
+	for rows.Next() {
+		var i ListBookmarkCollectionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Name,
+			&i.BookmarkCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listBookmarkedChirps = `-- name: ListBookmarkedChirps :many
+SELECT chirp_id FROM bookmarks
+WHERE user_id=$1 AND chirp_id=ANY($2::uuid[])
+`
+
+type ListBookmarkedChirpsParams struct {
+	UserID   uuid.UUID
+	ChirpIds []uuid.UUID
+}
+
+func (q *Queries) ListBookmarkedChirps(ctx context.Context, arg ListBookmarkedChirpsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, listBookmarkedChirps, arg.UserID, pq.Array(arg.ChirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var chirp_id uuid.UUID
+		if err := rows.Scan(&chirp_id); err != nil {
+			return nil, err
+		}
+		items = append(items, chirp_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listBookmarks = `-- name: ListBookmarks :many
+SELECT bookmarks.id, bookmarks.created_at, bookmarks.collection_id, chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.visibility
+FROM bookmarks
+JOIN chirps ON chirps.id=bookmarks.chirp_id
+WHERE bookmarks.user_id=$1
+  AND ($2::uuid IS NULL OR bookmarks.collection_id=$2::uuid)
+  AND ($3::timestamp IS NULL
+       OR (bookmarks.created_at, bookmarks.id) < ($3::timestamp, $4::uuid))
+ORDER BY bookmarks.created_at DESC, bookmarks.id DESC
+LIMIT $5
+`
+
+type ListBookmarksParams struct {
+	UserID          uuid.UUID
+	CollectionID    uuid.NullUUID
+	BeforeCreatedAt sql.NullTime
+	BeforeID        uuid.NullUUID
+	MaxResults      int32
+}
+
+type ListBookmarksRow struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	CollectionID uuid.NullUUID
+	Chirp        Chirp
+}
+
+func (q *Queries) ListBookmarks(ctx context.Context, arg ListBookmarksParams) ([]ListBookmarksRow, error) {
+	rows, err := q.db.QueryContext(ctx, listBookmarks,
+		arg.UserID,
+		arg.CollectionID,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListBookmarksRow
+	for rows.Next() {
+		var i ListBookmarksRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.CollectionID,
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const renameBookmarkCollection = `-- name: RenameBookmarkCollection :one
+UPDATE bookmark_collections
+SET updated_at=NOW(), name=$3
+WHERE id=$1 AND user_id=$2
+RETURNING id, created_at, updated_at, user_id, name
+`
+
+type RenameBookmarkCollectionParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) RenameBookmarkCollection(ctx context.Context, arg RenameBookmarkCollectionParams) (BookmarkCollection, error) {
+	row := q.db.QueryRowContext(ctx, renameBookmarkCollection, arg.ID, arg.UserID, arg.Name)
+	var i BookmarkCollection
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+	)
+	return i, err
+}
@@ -0,0 +1,149 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: chirp_events.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createChirpEvent = `-- name: CreateChirpEvent :one
+INSERT INTO chirp_events (created_at, type, chirp_id, user_id, body, visibility)
+SELECT NOW(), $1::text, $2::uuid, $3::uuid, $4::text, $5::text
+FROM (SELECT pg_advisory_xact_lock(hashtext('chirp_events'))) AS serialized
+RETURNING id, created_at, type, chirp_id, user_id, body, visibility
+`
+
+type CreateChirpEventParams struct {
+	Type       string
+	ChirpID    uuid.UUID
+	UserID     uuid.UUID
+	Body       string
+	Visibility string
+}
+
+// The lock is held until the inserting transaction ends, so events commit
+// in ID order and a client resuming after an ID can't miss a lower one that
+// committed later.
+func (q *Queries) CreateChirpEvent(ctx context.Context, arg CreateChirpEventParams) (ChirpEvent, error) {
+	row := q.db.QueryRowContext(ctx, createChirpEvent,
+		arg.Type,
+		arg.ChirpID,
+		arg.UserID,
+		arg.Body,
+		arg.Visibility,
+	)
+	var i ChirpEvent
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.Type,
+		&i.ChirpID,
+		&i.UserID,
+		&i.Body,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const deleteOldChirpEvents = `-- name: DeleteOldChirpEvents :execrows
+DELETE FROM chirp_events
+WHERE id IN (
+    SELECT id FROM chirp_events
+    WHERE created_at < $1::timestamp
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+`
+
+type DeleteOldChirpEventsParams struct {
+	Cutoff    time.Time
+	BatchSize int32
+}
+
+// Deletes up to batch_size events created before cutoff.
+func (q *Queries) DeleteOldChirpEvents(ctx context.Context, arg DeleteOldChirpEventsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteOldChirpEvents, arg.Cutoff, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getChirpEvent = `-- name: GetChirpEvent :one
+SELECT id, created_at, type, chirp_id, user_id, body, visibility FROM chirp_events WHERE id=$1
+`
+
+func (q *Queries) GetChirpEvent(ctx context.Context, id int64) (ChirpEvent, error) {
+	row := q.db.QueryRowContext(ctx, getChirpEvent, id)
+	var i ChirpEvent
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.Type,
+		&i.ChirpID,
+		&i.UserID,
+		&i.Body,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const listChirpEventsAfter = `-- name: ListChirpEventsAfter :many
+SELECT id, created_at, type, chirp_id, user_id, body, visibility FROM chirp_events
+WHERE id>$1
+ORDER BY id ASC
+LIMIT $2
+`
+
+type ListChirpEventsAfterParams struct {
+	ID    int64
+	Limit int32
+}
+
+func (q *Queries) ListChirpEventsAfter(ctx context.Context, arg ListChirpEventsAfterParams) ([]ChirpEvent, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpEventsAfter, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChirpEvent
+	for rows.Next() {
+		var i ChirpEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.Type,
+			&i.ChirpID,
+			&i.UserID,
+			&i.Body,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const notifyChirpEvent = `-- name: NotifyChirpEvent :exec
+SELECT pg_notify('chirp_events', $1::bigint::text)
+`
+
+// Only the ID is sent, since pg_notify payloads are limited to 8000 bytes;
+// listeners load the event itself.
+func (q *Queries) NotifyChirpEvent(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, notifyChirpEvent, id)
+	return err
+}
@@ -12,18 +12,19 @@ import (
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
-RETURNING id, created_at, updated_at, body, user_id
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, visibility)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3)
+RETURNING id, created_at, updated_at, body, user_id, visibility
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body       string
+	UserID     uuid.UUID
+	Visibility string
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID, arg.Visibility)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -31,6 +32,7 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.Visibility,
 	)
 	return i, err
 }
@@ -55,7 +57,7 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 }
 
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE id=$1
+SELECT id, created_at, updated_at, body, user_id, visibility FROM chirps WHERE id=$1
 `
 
 func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -67,12 +69,13 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.Visibility,
 	)
 	return i, err
 }
 
 const listChirps = `-- name: ListChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps ORDER BY created_at ASC
+SELECT id, created_at, updated_at, body, user_id, visibility FROM chirps ORDER BY created_at ASC
 `
 
 func (q *Queries) ListChirps(ctx context.Context) ([]Chirp, error) {
@@ -90,6 +93,7 @@ func (q *Queries) ListChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.Visibility,
 		); err != nil {
 			return nil, err
 		}
@@ -105,7 +109,7 @@ func (q *Queries) ListChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const listChirpsFromAuthor = `-- name: ListChirpsFromAuthor :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE user_id=$1 ORDER BY created_at ASC
+SELECT id, created_at, updated_at, body, user_id, visibility FROM chirps WHERE user_id=$1 ORDER BY created_at ASC
 `
 
 func (q *Queries) ListChirpsFromAuthor(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
@@ -123,6 +127,7 @@ func (q *Queries) ListChirpsFromAuthor(ctx context.Context, userID uuid.UUID) ([
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.Visibility,
 		); err != nil {
 			return nil, err
 		}
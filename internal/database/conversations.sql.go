@@ -0,0 +1,328 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: conversations.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const addConversationParticipant = `-- name: AddConversationParticipant :exec
+INSERT INTO conversation_participants (conversation_id, user_id, joined_at)
+VALUES ($1, $2, NOW())
+`
+
+type AddConversationParticipantParams struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+}
+
+func (q *Queries) AddConversationParticipant(ctx context.Context, arg AddConversationParticipantParams) error {
+	_, err := q.db.ExecContext(ctx, addConversationParticipant, arg.ConversationID, arg.UserID)
+	return err
+}
+
+const countUnreadMessages = `-- name: CountUnreadMessages :one
+SELECT COUNT(*) FROM messages m
+JOIN conversation_participants p ON p.conversation_id=m.conversation_id
+WHERE p.user_id=$1
+  AND m.sender_id<>p.user_id
+  AND (p.last_read_at IS NULL OR m.created_at>p.last_read_at)
+  AND NOT EXISTS (SELECT 1 FROM blocks b WHERE b.blocker_id=p.user_id AND b.blocked_id=m.sender_id)
+`
+
+func (q *Queries) CountUnreadMessages(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUnreadMessages, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createConversation = `-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, is_group)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1)
+RETURNING id, created_at, updated_at, is_group
+`
+
+func (q *Queries) CreateConversation(ctx context.Context, isGroup bool) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, createConversation, isGroup)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsGroup,
+	)
+	return i, err
+}
+
+const createMessage = `-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3)
+RETURNING id, created_at, conversation_id, sender_id, body
+`
+
+type CreateMessageParams struct {
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+	Body           string
+}
+
+func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error) {
+	row := q.db.QueryRowContext(ctx, createMessage, arg.ConversationID, arg.SenderID, arg.Body)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ConversationID,
+		&i.SenderID,
+		&i.Body,
+	)
+	return i, err
+}
+
+const getConversationForParticipant = `-- name: GetConversationForParticipant :one
+SELECT c.id, c.created_at, c.updated_at, c.is_group, p.last_read_at
+FROM conversations c
+JOIN conversation_participants p ON p.conversation_id=c.id
+WHERE c.id=$1 AND p.user_id=$2
+`
+
+type GetConversationForParticipantParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+type GetConversationForParticipantRow struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	IsGroup    bool
+	LastReadAt sql.NullTime
+}
+
+func (q *Queries) GetConversationForParticipant(ctx context.Context, arg GetConversationForParticipantParams) (GetConversationForParticipantRow, error) {
+	row := q.db.QueryRowContext(ctx, getConversationForParticipant, arg.ID, arg.UserID)
+	var i GetConversationForParticipantRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsGroup,
+		&i.LastReadAt,
+	)
+	return i, err
+}
+
+const getDirectConversation = `-- name: GetDirectConversation :one
+SELECT c.id, c.created_at, c.updated_at, c.is_group FROM conversations c
+JOIN conversation_participants p1 ON p1.conversation_id=c.id AND p1.user_id=$1
+JOIN conversation_participants p2 ON p2.conversation_id=c.id AND p2.user_id=$2
+WHERE NOT c.is_group
+LIMIT 1
+`
+
+type GetDirectConversationParams struct {
+	UserA uuid.UUID
+	UserB uuid.UUID
+}
+
+func (q *Queries) GetDirectConversation(ctx context.Context, arg GetDirectConversationParams) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, getDirectConversation, arg.UserA, arg.UserB)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsGroup,
+	)
+	return i, err
+}
+
+const getMessage = `-- name: GetMessage :one
+SELECT id, created_at, conversation_id, sender_id, body FROM messages WHERE id=$1 AND conversation_id=$2
+`
+
+type GetMessageParams struct {
+	ID             uuid.UUID
+	ConversationID uuid.UUID
+}
+
+func (q *Queries) GetMessage(ctx context.Context, arg GetMessageParams) (Message, error) {
+	row := q.db.QueryRowContext(ctx, getMessage, arg.ID, arg.ConversationID)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ConversationID,
+		&i.SenderID,
+		&i.Body,
+	)
+	return i, err
+}
+
+const listConversationParticipants = `-- name: ListConversationParticipants :many
+SELECT user_id FROM conversation_participants
+WHERE conversation_id=$1
+ORDER BY joined_at ASC, user_id ASC
+`
+
+func (q *Queries) ListConversationParticipants(ctx context.Context, conversationID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, listConversationParticipants, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var user_id uuid.UUID
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listConversationsForUser = `-- name: ListConversationsForUser :many
+SELECT c.id, c.created_at, c.updated_at, c.is_group, p.last_read_at,
+    (SELECT COUNT(*) FROM messages m
+     WHERE m.conversation_id=c.id
+       AND m.sender_id<>p.user_id
+       AND (p.last_read_at IS NULL OR m.created_at>p.last_read_at)
+       AND NOT EXISTS (SELECT 1 FROM blocks b WHERE b.blocker_id=p.user_id AND b.blocked_id=m.sender_id)
+    ) AS unread_count
+FROM conversations c
+JOIN conversation_participants p ON p.conversation_id=c.id
+WHERE p.user_id=$1
+ORDER BY c.updated_at DESC
+`
+
+type ListConversationsForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	IsGroup     bool
+	LastReadAt  sql.NullTime
+	UnreadCount int64
+}
+
+func (q *Queries) ListConversationsForUser(ctx context.Context, userID uuid.UUID) ([]ListConversationsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, listConversationsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListConversationsForUserRow
+	for rows.Next() {
+		var i ListConversationsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.IsGroup,
+			&i.LastReadAt,
+			&i.UnreadCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMessages = `-- name: ListMessages :many
+SELECT id, created_at, conversation_id, sender_id, body FROM messages
+WHERE conversation_id=$1
+  AND ($2::timestamp IS NULL
+       OR (created_at, id) < ($2::timestamp, $3::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type ListMessagesParams struct {
+	ConversationID  uuid.UUID
+	BeforeCreatedAt sql.NullTime
+	BeforeID        uuid.NullUUID
+	MaxResults      int32
+}
+
+func (q *Queries) ListMessages(ctx context.Context, arg ListMessagesParams) ([]Message, error) {
+	rows, err := q.db.QueryContext(ctx, listMessages,
+		arg.ConversationID,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ConversationID,
+			&i.SenderID,
+			&i.Body,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markConversationRead = `-- name: MarkConversationRead :exec
+UPDATE conversation_participants
+SET last_read_at=GREATEST(last_read_at, $3::timestamp)
+WHERE conversation_id=$1 AND user_id=$2
+`
+
+type MarkConversationReadParams struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+	ReadAt         time.Time
+}
+
+func (q *Queries) MarkConversationRead(ctx context.Context, arg MarkConversationReadParams) error {
+	_, err := q.db.ExecContext(ctx, markConversationRead, arg.ConversationID, arg.UserID, arg.ReadAt)
+	return err
+}
+
+const touchConversation = `-- name: TouchConversation :exec
+UPDATE conversations
+SET updated_at=NOW()
+WHERE id=$1
+`
+
+func (q *Queries) TouchConversation(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, touchConversation, id)
+	return err
+}
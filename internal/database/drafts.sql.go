@@ -0,0 +1,296 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: drafts.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const claimDueDraft = `-- name: ClaimDueDraft :one
+SELECT id, created_at, updated_at, user_id, body, media_ids, poll_options, poll_expires_at, publish_at, publish_error, visibility, publish_attempts, next_attempt_at FROM drafts
+WHERE publish_at <= $1::timestamp
+  AND (next_attempt_at IS NULL OR next_attempt_at <= $1::timestamp)
+ORDER BY publish_at
+LIMIT 1
+FOR UPDATE SKIP LOCKED
+`
+
+// Locked rows belong to another instance that's publishing them right now.
+func (q *Queries) ClaimDueDraft(ctx context.Context, now time.Time) (Draft, error) {
+	row := q.db.QueryRowContext(ctx, claimDueDraft, now)
+	var i Draft
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Body,
+		pq.Array(&i.MediaIds),
+		pq.Array(&i.PollOptions),
+		&i.PollExpiresAt,
+		&i.PublishAt,
+		&i.PublishError,
+		&i.Visibility,
+		&i.PublishAttempts,
+		&i.NextAttemptAt,
+	)
+	return i, err
+}
+
+const createDraft = `-- name: CreateDraft :one
+INSERT INTO drafts (id, created_at, updated_at, user_id, body, media_ids, poll_options, poll_expires_at, publish_at, visibility)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5, $6, $7)
+RETURNING id, created_at, updated_at, user_id, body, media_ids, poll_options, poll_expires_at, publish_at, publish_error, visibility, publish_attempts, next_attempt_at
+`
+
+type CreateDraftParams struct {
+	UserID        uuid.UUID
+	Body          string
+	MediaIds      []uuid.UUID
+	PollOptions   []string
+	PollExpiresAt sql.NullTime
+	PublishAt     sql.NullTime
+	Visibility    string
+}
+
+func (q *Queries) CreateDraft(ctx context.Context, arg CreateDraftParams) (Draft, error) {
+	row := q.db.QueryRowContext(ctx, createDraft,
+		arg.UserID,
+		arg.Body,
+		pq.Array(arg.MediaIds),
+		pq.Array(arg.PollOptions),
+		arg.PollExpiresAt,
+		arg.PublishAt,
+		arg.Visibility,
+	)
+	var i Draft
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Body,
+		pq.Array(&i.MediaIds),
+		pq.Array(&i.PollOptions),
+		&i.PollExpiresAt,
+		&i.PublishAt,
+		&i.PublishError,
+		&i.Visibility,
+		&i.PublishAttempts,
+		&i.NextAttemptAt,
+	)
+	return i, err
+}
+
+const deleteDraft = `-- name: DeleteDraft :execrows
+DELETE FROM drafts WHERE id=$1 AND user_id=$2
+`
+
+type DeleteDraftParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteDraft(ctx context.Context, arg DeleteDraftParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteDraft, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const failScheduledDraft = `-- name: FailScheduledDraft :exec
+UPDATE drafts
+SET updated_at=NOW(), publish_at=NULL, publish_error=$2, publish_attempts=0, next_attempt_at=NULL
+WHERE id=$1
+`
+
+type FailScheduledDraftParams struct {
+	ID           uuid.UUID
+	PublishError sql.NullString
+}
+
+func (q *Queries) FailScheduledDraft(ctx context.Context, arg FailScheduledDraftParams) error {
+	_, err := q.db.ExecContext(ctx, failScheduledDraft, arg.ID, arg.PublishError)
+	return err
+}
+
+const getDraft = `-- name: GetDraft :one
+SELECT id, created_at, updated_at, user_id, body, media_ids, poll_options, poll_expires_at, publish_at, publish_error, visibility, publish_attempts, next_attempt_at FROM drafts WHERE id=$1 AND user_id=$2
+`
+
+type GetDraftParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetDraft(ctx context.Context, arg GetDraftParams) (Draft, error) {
+	row := q.db.QueryRowContext(ctx, getDraft, arg.ID, arg.UserID)
+	var i Draft
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Body,
+		pq.Array(&i.MediaIds),
+		pq.Array(&i.PollOptions),
+		&i.PollExpiresAt,
+		&i.PublishAt,
+		&i.PublishError,
+		&i.Visibility,
+		&i.PublishAttempts,
+		&i.NextAttemptAt,
+	)
+	return i, err
+}
+
+const listDrafts = `-- name: ListDrafts :many
+SELECT id, created_at, updated_at, user_id, body, media_ids, poll_options, poll_expires_at, publish_at, publish_error, visibility, publish_attempts, next_attempt_at FROM drafts
+WHERE user_id=$1
+ORDER BY updated_at DESC
+`
+
+func (q *Queries) ListDrafts(ctx context.Context, userID uuid.UUID) ([]Draft, error) {
+	rows, err := q.db.QueryContext(ctx, listDrafts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Draft
+	for rows.Next() {
+		var i Draft
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Body,
+			pq.Array(&i.MediaIds),
+			pq.Array(&i.PollOptions),
+			&i.PollExpiresAt,
+			&i.PublishAt,
+			&i.PublishError,
+			&i.Visibility,
+			&i.PublishAttempts,
+			&i.NextAttemptAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockDraft = `-- name: LockDraft :one
+SELECT id, created_at, updated_at, user_id, body, media_ids, poll_options, poll_expires_at, publish_at, publish_error, visibility, publish_attempts, next_attempt_at FROM drafts WHERE id=$1 AND user_id=$2
+FOR UPDATE
+`
+
+type LockDraftParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) LockDraft(ctx context.Context, arg LockDraftParams) (Draft, error) {
+	row := q.db.QueryRowContext(ctx, lockDraft, arg.ID, arg.UserID)
+	var i Draft
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Body,
+		pq.Array(&i.MediaIds),
+		pq.Array(&i.PollOptions),
+		&i.PollExpiresAt,
+		&i.PublishAt,
+		&i.PublishError,
+		&i.Visibility,
+		&i.PublishAttempts,
+		&i.NextAttemptAt,
+	)
+	return i, err
+}
+
+const retryScheduledDraft = `-- name: RetryScheduledDraft :one
+UPDATE drafts
+SET publish_attempts=publish_attempts+1, next_attempt_at=$3::timestamp, publish_error=$2
+WHERE id=$1
+RETURNING publish_attempts
+`
+
+type RetryScheduledDraftParams struct {
+	ID            uuid.UUID
+	PublishError  sql.NullString
+	NextAttemptAt time.Time
+}
+
+func (q *Queries) RetryScheduledDraft(ctx context.Context, arg RetryScheduledDraftParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, retryScheduledDraft, arg.ID, arg.PublishError, arg.NextAttemptAt)
+	var publish_attempts int32
+	err := row.Scan(&publish_attempts)
+	return publish_attempts, err
+}
+
+const updateDraft = `-- name: UpdateDraft :one
+UPDATE drafts
+SET updated_at=NOW(), body=$3, media_ids=$4, poll_options=$5, poll_expires_at=$6, publish_at=$7, visibility=$8, publish_error=NULL, publish_attempts=0, next_attempt_at=NULL
+WHERE id=$1 AND user_id=$2
+RETURNING id, created_at, updated_at, user_id, body, media_ids, poll_options, poll_expires_at, publish_at, publish_error, visibility, publish_attempts, next_attempt_at
+`
+
+type UpdateDraftParams struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	Body          string
+	MediaIds      []uuid.UUID
+	PollOptions   []string
+	PollExpiresAt sql.NullTime
+	PublishAt     sql.NullTime
+	Visibility    string
+}
+
+func (q *Queries) UpdateDraft(ctx context.Context, arg UpdateDraftParams) (Draft, error) {
+	row := q.db.QueryRowContext(ctx, updateDraft,
+		arg.ID,
+		arg.UserID,
+		arg.Body,
+		pq.Array(arg.MediaIds),
+		pq.Array(arg.PollOptions),
+		arg.PollExpiresAt,
+		arg.PublishAt,
+		arg.Visibility,
+	)
+	var i Draft
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Body,
+		pq.Array(&i.MediaIds),
+		pq.Array(&i.PollOptions),
+		&i.PollExpiresAt,
+		&i.PublishAt,
+		&i.PublishError,
+		&i.Visibility,
+		&i.PublishAttempts,
+		&i.NextAttemptAt,
+	)
+	return i, err
+}
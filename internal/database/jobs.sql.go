@@ -0,0 +1,309 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: jobs.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const claimJob = `-- name: ClaimJob :one
+UPDATE jobs
+SET updated_at=NOW(), status='running', attempts=attempts+1, locked_until=$1::timestamp
+WHERE id = (
+    SELECT id FROM jobs
+    WHERE (status='queued' AND run_at <= $2::timestamp)
+       OR (status='running' AND locked_until <= $2::timestamp)
+    ORDER BY run_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, kind, payload, status, unique_key, attempts, max_attempts, run_at, locked_until, last_error, finished_at
+`
+
+type ClaimJobParams struct {
+	LockedUntil time.Time
+	Now         time.Time
+}
+
+// Takes the next due job, or one whose worker's lease ran out, and leases it
+// until locked_until. Locked rows are being claimed by another worker.
+func (q *Queries) ClaimJob(ctx context.Context, arg ClaimJobParams) (Job, error) {
+	row := q.db.QueryRowContext(ctx, claimJob, arg.LockedUntil, arg.Now)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Kind,
+		&i.Payload,
+		&i.Status,
+		&i.UniqueKey,
+		&i.Attempts,
+		&i.MaxAttempts,
+		&i.RunAt,
+		&i.LockedUntil,
+		&i.LastError,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const completeJob = `-- name: CompleteJob :execrows
+UPDATE jobs
+SET updated_at=NOW(), status='succeeded', locked_until=NULL, last_error=NULL, finished_at=NOW()
+WHERE id=$1 AND attempts=$2 AND status='running'
+`
+
+type CompleteJobParams struct {
+	ID       uuid.UUID
+	Attempts int32
+}
+
+// The finishing updates only apply to the attempt that claimed the job, so
+// a worker that outlived its lease can't overwrite the state of the worker
+// that reclaimed the job.
+func (q *Queries) CompleteJob(ctx context.Context, arg CompleteJobParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, completeJob, arg.ID, arg.Attempts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFinishedJobs = `-- name: DeleteFinishedJobs :execrows
+DELETE FROM jobs
+WHERE id IN (
+    SELECT id FROM jobs
+    WHERE status IN ('succeeded', 'dead') AND finished_at < $1::timestamp
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+`
+
+type DeleteFinishedJobsParams struct {
+	Cutoff    time.Time
+	BatchSize int32
+}
+
+// Deletes up to batch_size jobs that succeeded or died before cutoff.
+func (q *Queries) DeleteFinishedJobs(ctx context.Context, arg DeleteFinishedJobsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFinishedJobs, arg.Cutoff, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const enqueueJob = `-- name: EnqueueJob :one
+INSERT INTO jobs (id, created_at, updated_at, kind, payload, status, unique_key, max_attempts, run_at)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, 'queued', $3, $4, $5)
+ON CONFLICT (kind, unique_key) WHERE unique_key IS NOT NULL AND status IN ('queued', 'running')
+DO NOTHING
+RETURNING id, created_at, updated_at, kind, payload, status, unique_key, attempts, max_attempts, run_at, locked_until, last_error, finished_at
+`
+
+type EnqueueJobParams struct {
+	Kind        string
+	Payload     json.RawMessage
+	UniqueKey   sql.NullString
+	MaxAttempts int32
+	RunAt       time.Time
+}
+
+// Returns no rows when a job with the same kind and unique key is already
+// waiting or running.
+func (q *Queries) EnqueueJob(ctx context.Context, arg EnqueueJobParams) (Job, error) {
+	row := q.db.QueryRowContext(ctx, enqueueJob,
+		arg.Kind,
+		arg.Payload,
+		arg.UniqueKey,
+		arg.MaxAttempts,
+		arg.RunAt,
+	)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Kind,
+		&i.Payload,
+		&i.Status,
+		&i.UniqueKey,
+		&i.Attempts,
+		&i.MaxAttempts,
+		&i.RunAt,
+		&i.LockedUntil,
+		&i.LastError,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const getJob = `-- name: GetJob :one
+SELECT id, created_at, updated_at, kind, payload, status, unique_key, attempts, max_attempts, run_at, locked_until, last_error, finished_at FROM jobs WHERE id=$1
+`
+
+func (q *Queries) GetJob(ctx context.Context, id uuid.UUID) (Job, error) {
+	row := q.db.QueryRowContext(ctx, getJob, id)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Kind,
+		&i.Payload,
+		&i.Status,
+		&i.UniqueKey,
+		&i.Attempts,
+		&i.MaxAttempts,
+		&i.RunAt,
+		&i.LockedUntil,
+		&i.LastError,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const killJob = `-- name: KillJob :execrows
+UPDATE jobs
+SET updated_at=NOW(), status='dead', locked_until=NULL, last_error=$1, finished_at=NOW()
+WHERE id=$2 AND attempts=$3 AND status='running'
+`
+
+type KillJobParams struct {
+	LastError sql.NullString
+	ID        uuid.UUID
+	Attempts  int32
+}
+
+func (q *Queries) KillJob(ctx context.Context, arg KillJobParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, killJob, arg.LastError, arg.ID, arg.Attempts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listJobs = `-- name: ListJobs :many
+SELECT id, created_at, updated_at, kind, payload, status, unique_key, attempts, max_attempts, run_at, locked_until, last_error, finished_at FROM jobs
+WHERE ($1::text IS NULL OR status=$1::text)
+  AND ($2::text IS NULL OR kind=$2::text)
+  AND ($3::timestamp IS NULL
+       OR (created_at, id) < ($3::timestamp, $4::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $5
+`
+
+type ListJobsParams struct {
+	Status          sql.NullString
+	Kind            sql.NullString
+	BeforeCreatedAt sql.NullTime
+	BeforeID        uuid.NullUUID
+	MaxResults      int32
+}
+
+func (q *Queries) ListJobs(ctx context.Context, arg ListJobsParams) ([]Job, error) {
+	rows, err := q.db.QueryContext(ctx, listJobs,
+		arg.Status,
+		arg.Kind,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Job
+	for rows.Next() {
+		var i Job
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Kind,
+			&i.Payload,
+			&i.Status,
+			&i.UniqueKey,
+			&i.Attempts,
+			&i.MaxAttempts,
+			&i.RunAt,
+			&i.LockedUntil,
+			&i.LastError,
+			&i.FinishedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const requeueDeadJob = `-- name: RequeueDeadJob :one
+UPDATE jobs
+SET updated_at=NOW(), status='queued', attempts=0, run_at=NOW(), finished_at=NULL
+WHERE id=$1 AND status='dead'
+RETURNING id, created_at, updated_at, kind, payload, status, unique_key, attempts, max_attempts, run_at, locked_until, last_error, finished_at
+`
+
+// Gives a dead job a fresh set of attempts.
+func (q *Queries) RequeueDeadJob(ctx context.Context, id uuid.UUID) (Job, error) {
+	row := q.db.QueryRowContext(ctx, requeueDeadJob, id)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Kind,
+		&i.Payload,
+		&i.Status,
+		&i.UniqueKey,
+		&i.Attempts,
+		&i.MaxAttempts,
+		&i.RunAt,
+		&i.LockedUntil,
+		&i.LastError,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const retryJobLater = `-- name: RetryJobLater :execrows
+UPDATE jobs
+SET updated_at=NOW(), status='queued', locked_until=NULL, last_error=$1, run_at=$2
+WHERE id=$3 AND attempts=$4 AND status='running'
+`
+
+type RetryJobLaterParams struct {
+	LastError sql.NullString
+	RunAt     time.Time
+	ID        uuid.UUID
+	Attempts  int32
+}
+
+func (q *Queries) RetryJobLater(ctx context.Context, arg RetryJobLaterParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, retryJobLater,
+		arg.LastError,
+		arg.RunAt,
+		arg.ID,
+		arg.Attempts,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,390 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: media.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const attachMediaToChirp = `-- name: AttachMediaToChirp :exec
+INSERT INTO chirp_media (chirp_id, media_id, position)
+VALUES ($1, $2, $3)
+`
+
+type AttachMediaToChirpParams struct {
+	ChirpID  uuid.UUID
+	MediaID  uuid.UUID
+	Position int32
+}
+
+func (q *Queries) AttachMediaToChirp(ctx context.Context, arg AttachMediaToChirpParams) error {
+	_, err := q.db.ExecContext(ctx, attachMediaToChirp, arg.ChirpID, arg.MediaID, arg.Position)
+	return err
+}
+
+const claimMedia = `-- name: ClaimMedia :one
+UPDATE media
+SET updated_at=NOW(), status='processing'
+WHERE id=$1 AND status IN ('pending', 'processing')
+RETURNING id, created_at, updated_at, user_id, storage_key, mime_type, size_bytes, width, height, alt_text, status, blurhash
+`
+
+// Media is processed by one job at a time, so an upload left processing is
+// one whose previous attempt failed or outlived its job's lease.
+func (q *Queries) ClaimMedia(ctx context.Context, id uuid.UUID) (Media, error) {
+	row := q.db.QueryRowContext(ctx, claimMedia, id)
+	var i Media
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.StorageKey,
+		&i.MimeType,
+		&i.SizeBytes,
+		&i.Width,
+		&i.Height,
+		&i.AltText,
+		&i.Status,
+		&i.Blurhash,
+	)
+	return i, err
+}
+
+const createMedia = `-- name: CreateMedia :one
+INSERT INTO media (id, created_at, updated_at, user_id, storage_key, mime_type, size_bytes, width, height, alt_text)
+VALUES ($1, NOW(), NOW(), $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, created_at, updated_at, user_id, storage_key, mime_type, size_bytes, width, height, alt_text, status, blurhash
+`
+
+type CreateMediaParams struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	StorageKey string
+	MimeType   string
+	SizeBytes  int64
+	Width      int32
+	Height     int32
+	AltText    string
+}
+
+func (q *Queries) CreateMedia(ctx context.Context, arg CreateMediaParams) (Media, error) {
+	row := q.db.QueryRowContext(ctx, createMedia,
+		arg.ID,
+		arg.UserID,
+		arg.StorageKey,
+		arg.MimeType,
+		arg.SizeBytes,
+		arg.Width,
+		arg.Height,
+		arg.AltText,
+	)
+	var i Media
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.StorageKey,
+		&i.MimeType,
+		&i.SizeBytes,
+		&i.Width,
+		&i.Height,
+		&i.AltText,
+		&i.Status,
+		&i.Blurhash,
+	)
+	return i, err
+}
+
+const createMediaVariant = `-- name: CreateMediaVariant :exec
+INSERT INTO media_variants (media_id, name, storage_key, mime_type, size_bytes, width, height)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (media_id, name) DO UPDATE
+SET storage_key=EXCLUDED.storage_key, mime_type=EXCLUDED.mime_type, size_bytes=EXCLUDED.size_bytes, width=EXCLUDED.width, height=EXCLUDED.height
+`
+
+type CreateMediaVariantParams struct {
+	MediaID    uuid.UUID
+	Name       string
+	StorageKey string
+	MimeType   string
+	SizeBytes  int64
+	Width      int32
+	Height     int32
+}
+
+func (q *Queries) CreateMediaVariant(ctx context.Context, arg CreateMediaVariantParams) error {
+	_, err := q.db.ExecContext(ctx, createMediaVariant,
+		arg.MediaID,
+		arg.Name,
+		arg.StorageKey,
+		arg.MimeType,
+		arg.SizeBytes,
+		arg.Width,
+		arg.Height,
+	)
+	return err
+}
+
+const getChirpForMedia = `-- name: GetChirpForMedia :one
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.visibility FROM chirps
+JOIN chirp_media ON chirp_media.chirp_id=chirps.id
+WHERE chirp_media.media_id=$1
+`
+
+func (q *Queries) GetChirpForMedia(ctx context.Context, mediaID uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpForMedia, mediaID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const getMedia = `-- name: GetMedia :one
+SELECT id, created_at, updated_at, user_id, storage_key, mime_type, size_bytes, width, height, alt_text, status, blurhash FROM media WHERE id=$1
+`
+
+func (q *Queries) GetMedia(ctx context.Context, id uuid.UUID) (Media, error) {
+	row := q.db.QueryRowContext(ctx, getMedia, id)
+	var i Media
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.StorageKey,
+		&i.MimeType,
+		&i.SizeBytes,
+		&i.Width,
+		&i.Height,
+		&i.AltText,
+		&i.Status,
+		&i.Blurhash,
+	)
+	return i, err
+}
+
+const getMediaVariant = `-- name: GetMediaVariant :one
+SELECT media_id, name, storage_key, mime_type, size_bytes, width, height FROM media_variants WHERE media_id=$1 AND name=$2
+`
+
+type GetMediaVariantParams struct {
+	MediaID uuid.UUID
+	Name    string
+}
+
+func (q *Queries) GetMediaVariant(ctx context.Context, arg GetMediaVariantParams) (MediaVariant, error) {
+	row := q.db.QueryRowContext(ctx, getMediaVariant, arg.MediaID, arg.Name)
+	var i MediaVariant
+	err := row.Scan(
+		&i.MediaID,
+		&i.Name,
+		&i.StorageKey,
+		&i.MimeType,
+		&i.SizeBytes,
+		&i.Width,
+		&i.Height,
+	)
+	return i, err
+}
+
+const isMediaAttached = `-- name: IsMediaAttached :one
+SELECT EXISTS (SELECT 1 FROM chirp_media WHERE media_id=$1)
+`
+
+func (q *Queries) IsMediaAttached(ctx context.Context, mediaID uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isMediaAttached, mediaID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listMediaForChirps = `-- name: ListMediaForChirps :many
+SELECT chirp_media.chirp_id, media.id, media.created_at, media.updated_at, media.user_id, media.storage_key, media.mime_type, media.size_bytes, media.width, media.height, media.alt_text, media.status, media.blurhash
+FROM chirp_media
+JOIN media ON media.id=chirp_media.media_id
+WHERE chirp_media.chirp_id=ANY($1::uuid[])
+ORDER BY chirp_media.chirp_id, chirp_media.position
+`
+
+type ListMediaForChirpsRow struct {
+	ChirpID uuid.UUID
+	Media   Media
+}
+
+func (q *Queries) ListMediaForChirps(ctx context.Context, chirpIds []uuid.UUID) ([]ListMediaForChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listMediaForChirps, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMediaForChirpsRow
+	for rows.Next() {
+		var i ListMediaForChirpsRow
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.Media.ID,
+			&i.Media.CreatedAt,
+			&i.Media.UpdatedAt,
+			&i.Media.UserID,
+			&i.Media.StorageKey,
+			&i.Media.MimeType,
+			&i.Media.SizeBytes,
+			&i.Media.Width,
+			&i.Media.Height,
+			&i.Media.AltText,
+			&i.Media.Status,
+			&i.Media.Blurhash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaVariants = `-- name: ListMediaVariants :many
+SELECT media_id, name, storage_key, mime_type, size_bytes, width, height FROM media_variants
+WHERE media_id=ANY($1::uuid[])
+ORDER BY media_id, width
+`
+
+func (q *Queries) ListMediaVariants(ctx context.Context, mediaIds []uuid.UUID) ([]MediaVariant, error) {
+	rows, err := q.db.QueryContext(ctx, listMediaVariants, pq.Array(mediaIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MediaVariant
+	for rows.Next() {
+		var i MediaVariant
+		if err := rows.Scan(
+			&i.MediaID,
+			&i.Name,
+			&i.StorageKey,
+			&i.MimeType,
+			&i.SizeBytes,
+			&i.Width,
+			&i.Height,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markMediaFailed = `-- name: MarkMediaFailed :exec
+UPDATE media
+SET updated_at=NOW(), status='failed'
+WHERE id=$1
+`
+
+func (q *Queries) MarkMediaFailed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markMediaFailed, id)
+	return err
+}
+
+const markMediaReady = `-- name: MarkMediaReady :one
+UPDATE media
+SET updated_at=NOW(), status='ready', storage_key=$2, mime_type=$3, size_bytes=$4, width=$5, height=$6, blurhash=$7
+WHERE id=$1
+RETURNING id, created_at, updated_at, user_id, storage_key, mime_type, size_bytes, width, height, alt_text, status, blurhash
+`
+
+type MarkMediaReadyParams struct {
+	ID         uuid.UUID
+	StorageKey string
+	MimeType   string
+	SizeBytes  int64
+	Width      int32
+	Height     int32
+	Blurhash   sql.NullString
+}
+
+func (q *Queries) MarkMediaReady(ctx context.Context, arg MarkMediaReadyParams) (Media, error) {
+	row := q.db.QueryRowContext(ctx, markMediaReady,
+		arg.ID,
+		arg.StorageKey,
+		arg.MimeType,
+		arg.SizeBytes,
+		arg.Width,
+		arg.Height,
+		arg.Blurhash,
+	)
+	var i Media
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.StorageKey,
+		&i.MimeType,
+		&i.SizeBytes,
+		&i.Width,
+		&i.Height,
+		&i.AltText,
+		&i.Status,
+		&i.Blurhash,
+	)
+	return i, err
+}
+
+const updateMediaAltText = `-- name: UpdateMediaAltText :one
+UPDATE media
+SET updated_at=NOW(), alt_text=$3
+WHERE id=$1 AND user_id=$2
+RETURNING id, created_at, updated_at, user_id, storage_key, mime_type, size_bytes, width, height, alt_text, status, blurhash
+`
+
+type UpdateMediaAltTextParams struct {
+	ID      uuid.UUID
+	UserID  uuid.UUID
+	AltText string
+}
+
+func (q *Queries) UpdateMediaAltText(ctx context.Context, arg UpdateMediaAltTextParams) (Media, error) {
+	row := q.db.QueryRowContext(ctx, updateMediaAltText, arg.ID, arg.UserID, arg.AltText)
+	var i Media
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.StorageKey,
+		&i.MimeType,
+		&i.SizeBytes,
+		&i.Width,
+		&i.Height,
+		&i.AltText,
+		&i.Status,
+		&i.Blurhash,
+	)
+	return i, err
+}
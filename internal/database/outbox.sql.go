@@ -0,0 +1,176 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: outbox.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const claimOutboxBatch = `-- name: ClaimOutboxBatch :many
+WITH claimed AS (
+    UPDATE outbox
+    SET locked_until=$1::timestamp
+    WHERE id IN (
+        SELECT id FROM outbox
+        WHERE published_at IS NULL AND failed_at IS NULL AND next_attempt_at <= $2::timestamp
+          AND (locked_until IS NULL OR locked_until <= $2::timestamp)
+        ORDER BY created_at, id
+        LIMIT $3
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING id, created_at, aggregate_type, aggregate_id, event_type, payload, attempts, next_attempt_at, last_error, published_at, locked_until, failed_at
+)
+SELECT id, created_at, aggregate_type, aggregate_id, event_type, payload, attempts, next_attempt_at, last_error, published_at, locked_until, failed_at FROM claimed
+ORDER BY created_at, id
+`
+
+type ClaimOutboxBatchParams struct {
+	LockedUntil time.Time
+	Now         time.Time
+	MaxResults  int32
+}
+
+type ClaimOutboxBatchRow struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	AggregateType string
+	AggregateID   uuid.UUID
+	EventType     string
+	Payload       json.RawMessage
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastError     sql.NullString
+	PublishedAt   sql.NullTime
+	LockedUntil   sql.NullTime
+	FailedAt      sql.NullTime
+}
+
+// Leases a batch of due events until locked_until, so they can be published
+// outside a transaction and a relay that dies mid-batch only delays them.
+// Locked rows are being claimed by another relay.
+func (q *Queries) ClaimOutboxBatch(ctx context.Context, arg ClaimOutboxBatchParams) ([]ClaimOutboxBatchRow, error) {
+	rows, err := q.db.QueryContext(ctx, claimOutboxBatch, arg.LockedUntil, arg.Now, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimOutboxBatchRow
+	for rows.Next() {
+		var i ClaimOutboxBatchRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.AggregateType,
+			&i.AggregateID,
+			&i.EventType,
+			&i.Payload,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LastError,
+			&i.PublishedAt,
+			&i.LockedUntil,
+			&i.FailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createOutboxMessage = `-- name: CreateOutboxMessage :exec
+INSERT INTO outbox (id, created_at, aggregate_type, aggregate_id, event_type, payload, next_attempt_at)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4, NOW())
+`
+
+type CreateOutboxMessageParams struct {
+	AggregateType string
+	AggregateID   uuid.UUID
+	EventType     string
+	Payload       json.RawMessage
+}
+
+func (q *Queries) CreateOutboxMessage(ctx context.Context, arg CreateOutboxMessageParams) error {
+	_, err := q.db.ExecContext(ctx, createOutboxMessage,
+		arg.AggregateType,
+		arg.AggregateID,
+		arg.EventType,
+		arg.Payload,
+	)
+	return err
+}
+
+const deleteFinishedOutbox = `-- name: DeleteFinishedOutbox :execrows
+DELETE FROM outbox
+WHERE id IN (
+    SELECT id FROM outbox
+    WHERE published_at < $1::timestamp OR failed_at < $1::timestamp
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+`
+
+type DeleteFinishedOutboxParams struct {
+	Cutoff    time.Time
+	BatchSize int32
+}
+
+// Deletes up to batch_size events that were published or given up on before
+// cutoff.
+func (q *Queries) DeleteFinishedOutbox(ctx context.Context, arg DeleteFinishedOutboxParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFinishedOutbox, arg.Cutoff, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markOutboxPublished = `-- name: MarkOutboxPublished :exec
+UPDATE outbox
+SET published_at=NOW(), attempts=attempts+1, last_error=NULL, locked_until=NULL
+WHERE id=$1
+`
+
+func (q *Queries) MarkOutboxPublished(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markOutboxPublished, id)
+	return err
+}
+
+const recordOutboxFailure = `-- name: RecordOutboxFailure :exec
+UPDATE outbox
+SET attempts=attempts+1, last_error=$1, next_attempt_at=$2, locked_until=NULL,
+    failed_at=CASE WHEN attempts+1 >= $3::int THEN NOW() END
+WHERE id=$4
+`
+
+type RecordOutboxFailureParams struct {
+	LastError     sql.NullString
+	NextAttemptAt time.Time
+	MaxAttempts   int32
+	ID            uuid.UUID
+}
+
+// Gives up on the event once it has failed max_attempts times.
+func (q *Queries) RecordOutboxFailure(ctx context.Context, arg RecordOutboxFailureParams) error {
+	_, err := q.db.ExecContext(ctx, recordOutboxFailure,
+		arg.LastError,
+		arg.NextAttemptAt,
+		arg.MaxAttempts,
+		arg.ID,
+	)
+	return err
+}
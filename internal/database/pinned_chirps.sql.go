@@ -0,0 +1,155 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: pinned_chirps.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const countPinnedChirps = `-- name: CountPinnedChirps :one
+SELECT COUNT(*) FROM pinned_chirps WHERE user_id=$1
+`
+
+func (q *Queries) CountPinnedChirps(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPinnedChirps, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const listPinnedAmong = `-- name: ListPinnedAmong :many
+SELECT chirp_id FROM pinned_chirps
+WHERE chirp_id=ANY($1::uuid[])
+`
+
+func (q *Queries) ListPinnedAmong(ctx context.Context, chirpIds []uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, listPinnedAmong, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var chirp_id uuid.UUID
+		if err := rows.Scan(&chirp_id); err != nil {
+			return nil, err
+		}
+		items = append(items, chirp_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPinnedChirps = `-- name: ListPinnedChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.visibility
+FROM pinned_chirps
+JOIN chirps ON chirps.id=pinned_chirps.chirp_id
+WHERE pinned_chirps.user_id=$1
+ORDER BY pinned_chirps.position
+`
+
+type ListPinnedChirpsRow struct {
+	Chirp Chirp
+}
+
+func (q *Queries) ListPinnedChirps(ctx context.Context, userID uuid.UUID) ([]ListPinnedChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPinnedChirps, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPinnedChirpsRow
+	for rows.Next() {
+		var i ListPinnedChirpsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const pinChirp = `-- name: PinChirp :execrows
+INSERT INTO pinned_chirps (user_id, chirp_id, position, created_at)
+VALUES (
+    $1,
+    $2,
+    (SELECT COALESCE(MAX(position) + 1, 0) FROM pinned_chirps WHERE user_id=$1),
+    NOW()
+)
+ON CONFLICT (user_id, chirp_id) DO NOTHING
+`
+
+type PinChirpParams struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) PinChirp(ctx context.Context, arg PinChirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, pinChirp, arg.UserID, arg.ChirpID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setPinPosition = `-- name: SetPinPosition :execrows
+UPDATE pinned_chirps
+SET position=$3
+WHERE user_id=$1 AND chirp_id=$2
+`
+
+type SetPinPositionParams struct {
+	UserID   uuid.UUID
+	ChirpID  uuid.UUID
+	Position int32
+}
+
+func (q *Queries) SetPinPosition(ctx context.Context, arg SetPinPositionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setPinPosition, arg.UserID, arg.ChirpID, arg.Position)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const unpinChirp = `-- name: UnpinChirp :execrows
+DELETE FROM pinned_chirps WHERE user_id=$1 AND chirp_id=$2
+`
+
+type UnpinChirpParams struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) UnpinChirp(ctx context.Context, arg UnpinChirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unpinChirp, arg.UserID, arg.ChirpID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
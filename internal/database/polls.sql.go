@@ -0,0 +1,186 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: polls.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createPoll = `-- name: CreatePoll :exec
+INSERT INTO polls (chirp_id, expires_at)
+VALUES ($1, $2)
+`
+
+type CreatePollParams struct {
+	ChirpID   uuid.UUID
+	ExpiresAt time.Time
+}
+
+func (q *Queries) CreatePoll(ctx context.Context, arg CreatePollParams) error {
+	_, err := q.db.ExecContext(ctx, createPoll, arg.ChirpID, arg.ExpiresAt)
+	return err
+}
+
+const createPollOption = `-- name: CreatePollOption :exec
+INSERT INTO poll_options (chirp_id, position, text)
+VALUES ($1, $2, $3)
+`
+
+type CreatePollOptionParams struct {
+	ChirpID  uuid.UUID
+	Position int32
+	Text     string
+}
+
+func (q *Queries) CreatePollOption(ctx context.Context, arg CreatePollOptionParams) error {
+	_, err := q.db.ExecContext(ctx, createPollOption, arg.ChirpID, arg.Position, arg.Text)
+	return err
+}
+
+const createPollVote = `-- name: CreatePollVote :execrows
+INSERT INTO poll_votes (chirp_id, user_id, position, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (chirp_id, user_id) DO NOTHING
+`
+
+type CreatePollVoteParams struct {
+	ChirpID  uuid.UUID
+	UserID   uuid.UUID
+	Position int32
+}
+
+func (q *Queries) CreatePollVote(ctx context.Context, arg CreatePollVoteParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createPollVote, arg.ChirpID, arg.UserID, arg.Position)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getPoll = `-- name: GetPoll :one
+SELECT chirp_id, expires_at FROM polls WHERE chirp_id=$1
+`
+
+func (q *Queries) GetPoll(ctx context.Context, chirpID uuid.UUID) (Poll, error) {
+	row := q.db.QueryRowContext(ctx, getPoll, chirpID)
+	var i Poll
+	err := row.Scan(&i.ChirpID, &i.ExpiresAt)
+	return i, err
+}
+
+const listPollResults = `-- name: ListPollResults :many
+SELECT poll_options.chirp_id, poll_options.position, poll_options.text, COUNT(poll_votes.user_id) AS votes
+FROM poll_options
+LEFT JOIN poll_votes ON poll_votes.chirp_id=poll_options.chirp_id AND poll_votes.position=poll_options.position
+WHERE poll_options.chirp_id=ANY($1::uuid[])
+GROUP BY poll_options.chirp_id, poll_options.position, poll_options.text
+ORDER BY poll_options.chirp_id, poll_options.position
+`
+
+type ListPollResultsRow struct {
+	ChirpID  uuid.UUID
+	Position int32
+	Text     string
+	Votes    int64
+}
+
+func (q *Queries) ListPollResults(ctx context.Context, chirpIds []uuid.UUID) ([]ListPollResultsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPollResults, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPollResultsRow
+	for rows.Next() {
+		var i ListPollResultsRow
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.Position,
+			&i.Text,
+			&i.Votes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPollVotesByUser = `-- name: ListPollVotesByUser :many
+SELECT chirp_id, position FROM poll_votes
+WHERE user_id=$1 AND chirp_id=ANY($2::uuid[])
+`
+
+type ListPollVotesByUserParams struct {
+	UserID   uuid.UUID
+	ChirpIds []uuid.UUID
+}
+
+type ListPollVotesByUserRow struct {
+	ChirpID  uuid.UUID
+	Position int32
+}
+
+func (q *Queries) ListPollVotesByUser(ctx context.Context, arg ListPollVotesByUserParams) ([]ListPollVotesByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPollVotesByUser, arg.UserID, pq.Array(arg.ChirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPollVotesByUserRow
+	for rows.Next() {
+		var i ListPollVotesByUserRow
+		if err := rows.Scan(&i.ChirpID, &i.Position); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPollsForChirps = `-- name: ListPollsForChirps :many
+SELECT chirp_id, expires_at FROM polls
+WHERE chirp_id=ANY($1::uuid[])
+`
+
+func (q *Queries) ListPollsForChirps(ctx context.Context, chirpIds []uuid.UUID) ([]Poll, error) {
+	rows, err := q.db.QueryContext(ctx, listPollsForChirps, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Poll
+	for rows.Next() {
+		var i Poll
+		if err := rows.Scan(&i.ChirpID, &i.ExpiresAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,109 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: subscriptions.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const expireSubscriptions = `-- name: ExpireSubscriptions :many
+UPDATE subscriptions
+SET updated_at=NOW(), status='expired'
+WHERE status <> 'expired' AND current_period_end <= $1
+RETURNING user_id, plan, current_period_end
+`
+
+type ExpireSubscriptionsRow struct {
+	UserID           uuid.UUID
+	Plan             string
+	CurrentPeriodEnd time.Time
+}
+
+// Returns the users whose subscription lapsed.
+func (q *Queries) ExpireSubscriptions(ctx context.Context, now time.Time) ([]ExpireSubscriptionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, expireSubscriptions, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ExpireSubscriptionsRow
+	for rows.Next() {
+		var i ExpireSubscriptionsRow
+		if err := rows.Scan(&i.UserID, &i.Plan, &i.CurrentPeriodEnd); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSubscription = `-- name: GetSubscription :one
+SELECT id, created_at, updated_at, user_id, plan, status, current_period_start, current_period_end FROM subscriptions WHERE user_id=$1
+`
+
+func (q *Queries) GetSubscription(ctx context.Context, userID uuid.UUID) (Subscription, error) {
+	row := q.db.QueryRowContext(ctx, getSubscription, userID)
+	var i Subscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Plan,
+		&i.Status,
+		&i.CurrentPeriodStart,
+		&i.CurrentPeriodEnd,
+	)
+	return i, err
+}
+
+const upsertSubscription = `-- name: UpsertSubscription :one
+INSERT INTO subscriptions (id, created_at, updated_at, user_id, plan, status, current_period_start, current_period_end)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5)
+ON CONFLICT (user_id) DO UPDATE
+SET updated_at=NOW(), plan=EXCLUDED.plan, status=EXCLUDED.status,
+    current_period_start=EXCLUDED.current_period_start, current_period_end=EXCLUDED.current_period_end
+RETURNING id, created_at, updated_at, user_id, plan, status, current_period_start, current_period_end
+`
+
+type UpsertSubscriptionParams struct {
+	UserID             uuid.UUID
+	Plan               string
+	Status             string
+	CurrentPeriodStart time.Time
+	CurrentPeriodEnd   time.Time
+}
+
+func (q *Queries) UpsertSubscription(ctx context.Context, arg UpsertSubscriptionParams) (Subscription, error) {
+	row := q.db.QueryRowContext(ctx, upsertSubscription,
+		arg.UserID,
+		arg.Plan,
+		arg.Status,
+		arg.CurrentPeriodStart,
+		arg.CurrentPeriodEnd,
+	)
+	var i Subscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Plan,
+		&i.Status,
+		&i.CurrentPeriodStart,
+		&i.CurrentPeriodEnd,
+	)
+	return i, err
+}
@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -90,13 +91,52 @@ func (q *Queries) GetUser(ctx context.Context, email string) (User, error) {
 	return i, err
 }
 
-const upgradeUserToRed = `-- name: UpgradeUserToRed :exec
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users WHERE id=$1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+	)
+	return i, err
+}
+
+const lockUser = `-- name: LockUser :exec
+SELECT id FROM users WHERE id=$1 FOR UPDATE
+`
+
+func (q *Queries) LockUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, lockUser, id)
+	return err
+}
+
+const syncUserChirpyRed = `-- name: SyncUserChirpyRed :exec
 UPDATE users
-SET updated_at=NOW(), is_chirpy_red=true
-WHERE id=$1
+SET updated_at=NOW(), is_chirpy_red=EXISTS (
+    SELECT 1 FROM subscriptions
+    WHERE subscriptions.user_id=users.id
+      AND status IN ('active', 'past_due')
+      AND current_period_end > $1
+)
+WHERE users.id=$2
 `
 
-func (q *Queries) UpgradeUserToRed(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, upgradeUserToRed, id)
+type SyncUserChirpyRedParams struct {
+	Now    time.Time
+	UserID uuid.UUID
+}
+
+// is_chirpy_red caches whether the user's subscription entitles them to
+// Chirpy Red at now.
+func (q *Queries) SyncUserChirpyRed(ctx context.Context, arg SyncUserChirpyRedParams) error {
+	_, err := q.db.ExecContext(ctx, syncUserChirpyRed, arg.Now, arg.UserID)
 	return err
 }
@@ -0,0 +1,110 @@
+package entitlements
+
+import (
+	"chirpy/internal/subscriptions"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// PlanFree holds the capabilities of users without an entitling
+// subscription.
+const PlanFree = "free"
+
+// Capabilities are the limits that come with a plan.
+type Capabilities struct {
+	MaxChirpLength int `json:"max_chirp_length"`
+	MaxPins        int `json:"max_pins"`
+	MaxMedia       int `json:"max_media"`
+	// RateLimit is how many chirps and uploads a user can post per minute.
+	RateLimit         int `json:"rate_limit_per_minute"`
+	EditWindowSeconds int `json:"edit_window_seconds"`
+}
+
+// EditWindow is how long after posting a chirp can still be edited.
+func (c Capabilities) EditWindow() time.Duration {
+	return time.Duration(c.EditWindowSeconds) * time.Second
+}
+
+// Config maps plan names to their capabilities.
+type Config struct {
+	Plans map[string]Capabilities `json:"plans"`
+}
+
+// Default is the configuration used when none is given.
+func Default() Config {
+	return Config{Plans: map[string]Capabilities{
+		PlanFree: {
+			MaxChirpLength: 140,
+			MaxPins:        1,
+			MaxMedia:       4,
+			RateLimit:      10,
+		},
+		subscriptions.DefaultPlan: {
+			MaxChirpLength:    280,
+			MaxPins:           5,
+			MaxMedia:          4,
+			RateLimit:         30,
+			EditWindowSeconds: 30 * 60,
+		},
+	}}
+}
+
+// Load reads a JSON configuration such as
+//
+//	{"plans": {"free": {"max_chirp_length": 140, ...}, "chirpy_red": {...}}}
+//
+// Every plan must set every limit; the free plan is required.
+func Load(r io.Reader) (Config, error) {
+	config := Config{}
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return Config{}, fmt.Errorf("decoding entitlements: %w", err)
+	}
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// LoadFile loads the configuration in path.
+func LoadFile(path string) (Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Config{}, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+func (c Config) Validate() error {
+	if _, ok := c.Plans[PlanFree]; !ok {
+		return errors.New("entitlements must configure the free plan")
+	}
+	for plan, caps := range c.Plans {
+		if caps.MaxChirpLength <= 0 || caps.MaxPins <= 0 || caps.MaxMedia < 0 || caps.RateLimit <= 0 || caps.EditWindowSeconds < 0 {
+			return fmt.Errorf("invalid limits for plan %q", plan)
+		}
+	}
+	return nil
+}
+
+// For returns the capabilities of a user on plan. Users who aren't entitled
+// to their plan get the free plan; entitled users on a plan that isn't
+// configured get the default paid plan's, if there's one.
+func (c Config) For(plan string, entitled bool) Capabilities {
+	if !entitled {
+		return c.Plans[PlanFree]
+	}
+	if caps, ok := c.Plans[plan]; ok {
+		return caps
+	}
+	if caps, ok := c.Plans[subscriptions.DefaultPlan]; ok {
+		return caps
+	}
+	return c.Plans[PlanFree]
+}
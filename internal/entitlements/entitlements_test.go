@@ -0,0 +1,89 @@
+package entitlements
+
+import (
+	"chirpy/internal/subscriptions"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{
+			name:    "Valid config",
+			input:   `{"plans": {"free": {"max_chirp_length": 140, "max_pins": 1, "max_media": 4, "rate_limit_per_minute": 10}}}`,
+			wantErr: false,
+		},
+		{
+			name:    "Missing free plan",
+			input:   `{"plans": {"chirpy_red": {"max_chirp_length": 280, "max_pins": 5, "max_media": 4, "rate_limit_per_minute": 30}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "Missing limit",
+			input:   `{"plans": {"free": {"max_chirp_length": 140, "max_media": 4, "rate_limit_per_minute": 10}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "Unknown field",
+			input:   `{"plans": {"free": {"max_chirp_length": 140, "max_pins": 1, "max_media": 4, "rate_limit_per_minute": 10, "max_polls": 1}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "Malformed JSON",
+			input:   `{"plans":`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFor(t *testing.T) {
+	config := Default()
+	yearly := Capabilities{MaxChirpLength: 500, MaxPins: 10, MaxMedia: 8, RateLimit: 60}
+	config.Plans["yearly"] = yearly
+	tests := []struct {
+		name     string
+		plan     string
+		entitled bool
+		want     Capabilities
+	}{
+		{
+			name:     "Not entitled",
+			plan:     "yearly",
+			entitled: false,
+			want:     config.Plans[PlanFree],
+		},
+		{
+			name:     "Configured plan",
+			plan:     "yearly",
+			entitled: true,
+			want:     yearly,
+		},
+		{
+			name:     "Unconfigured plan",
+			plan:     "monthly",
+			entitled: true,
+			want:     config.Plans[subscriptions.DefaultPlan],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := config.For(tt.plan, tt.entitled); got != tt.want {
+				t.Errorf("For() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
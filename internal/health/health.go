@@ -0,0 +1,159 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Statuses of a check and of a report as a whole. A report is degraded when
+// only checks that aren't critical failed.
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusFail     = "fail"
+)
+
+// Check is one dependency check. A failing critical check makes the service
+// unready.
+type Check struct {
+	Name     string
+	Critical bool
+	Run      func(ctx context.Context) error
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of a set of checks.
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Run runs the checks concurrently, each with the given timeout, and
+// reports the results in the order of checks.
+func Run(ctx context.Context, timeout time.Duration, checks []Check) Report {
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			start := time.Now()
+			err := check.Run(checkCtx)
+			results[i] = Result{
+				Name:      check.Name,
+				Status:    StatusOK,
+				Critical:  check.Critical,
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				results[i].Status = StatusFail
+				results[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+	report := Report{Status: StatusOK, Checks: results}
+	for _, r := range results {
+		switch {
+		case r.Status == StatusOK:
+		case r.Critical:
+			report.Status = StatusFail
+		case report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// Ready reports whether every critical check passed.
+func (r Report) Ready() bool {
+	return r.Status != StatusFail
+}
+
+// Heartbeats tracks when each background worker last made progress. A
+// worker is stale once it has missed three of its intervals, plus a minute
+// of grace for slow work.
+type Heartbeats struct {
+	mu    sync.Mutex
+	beats map[string]heartbeat
+}
+
+type heartbeat struct {
+	at       time.Time
+	interval time.Duration
+}
+
+const staleGrace = time.Minute
+
+// NewHeartbeats returns Heartbeats tracking no workers yet.
+func NewHeartbeats() *Heartbeats {
+	return &Heartbeats{beats: map[string]heartbeat{}}
+}
+
+// Beat records that the named worker, which wakes up every interval, made
+// progress at now. Workers sharing a name count as one.
+func (h *Heartbeats) Beat(name string, interval time.Duration, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.beats[name] = heartbeat{at: now, interval: interval}
+}
+
+// Check fails if any worker is stale at now.
+func (h *Heartbeats) Check(now time.Time) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stale := []string{}
+	for name, beat := range h.beats {
+		if age := now.Sub(beat.at); age > 3*beat.interval+staleGrace {
+			stale = append(stale, fmt.Sprintf("%s (last seen %s ago)", name, age.Round(time.Second)))
+		}
+	}
+	if len(stale) > 0 {
+		slices.Sort(stale)
+		return fmt.Errorf("stale workers: %s", strings.Join(stale, ", "))
+	}
+	return nil
+}
+
+// SchemaVersion returns the highest goose migration version among the
+// NNN_name.sql files in fsys.
+func SchemaVersion(fsys fs.FS) (int64, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return 0, err
+	}
+	var latest int64
+	for _, file := range files {
+		prefix, _, ok := strings.Cut(path.Base(file), "_")
+		if !ok {
+			return 0, fmt.Errorf("migration %s has no version prefix", file)
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s has no version prefix", file)
+		}
+		latest = max(latest, version)
+	}
+	if latest == 0 {
+		return 0, errors.New("no migrations found")
+	}
+	return latest, nil
+}
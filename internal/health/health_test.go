@@ -0,0 +1,142 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	fail := func(context.Context) error { return errors.New("down") }
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	tests := []struct {
+		name      string
+		checks    []Check
+		want      string
+		wantReady bool
+	}{
+		{
+			name:      "All pass",
+			checks:    []Check{{Name: "database", Critical: true, Run: ok}, {Name: "workers", Run: ok}},
+			want:      StatusOK,
+			wantReady: true,
+		},
+		{
+			name:      "Non-critical failure",
+			checks:    []Check{{Name: "database", Critical: true, Run: ok}, {Name: "workers", Run: fail}},
+			want:      StatusDegraded,
+			wantReady: true,
+		},
+		{
+			name:      "Critical failure",
+			checks:    []Check{{Name: "database", Critical: true, Run: fail}, {Name: "workers", Run: fail}},
+			want:      StatusFail,
+			wantReady: false,
+		},
+		{
+			name:      "Timeout",
+			checks:    []Check{{Name: "database", Critical: true, Run: slow}},
+			want:      StatusFail,
+			wantReady: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Run(context.Background(), 10*time.Millisecond, tt.checks)
+			if got.Status != tt.want || got.Ready() != tt.wantReady {
+				t.Errorf("Run() = %+v, want status %s, ready %v", got, tt.want, tt.wantReady)
+			}
+			for i, r := range got.Checks {
+				if r.Name != tt.checks[i].Name {
+					t.Errorf("Run() check %d = %s, want %s", i, r.Name, tt.checks[i].Name)
+				}
+				if (r.Status == StatusFail) != (r.Error != "") {
+					t.Errorf("Run() check %s = %+v, want an error only on failure", r.Name, r)
+				}
+			}
+		})
+	}
+}
+
+func TestHeartbeats(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		last    time.Time
+		wantErr bool
+	}{
+		{
+			name:    "Recent",
+			last:    now.Add(-30 * time.Second),
+			wantErr: false,
+		},
+		{
+			name:    "Within grace",
+			last:    now.Add(-90 * time.Second),
+			wantErr: false,
+		},
+		{
+			name:    "Stale",
+			last:    now.Add(-2 * time.Minute),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHeartbeats()
+			h.Beat("scheduler", 10*time.Second, tt.last)
+			if err := h.Check(now); (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		want    int64
+		wantErr bool
+	}{
+		{
+			name:  "Latest version",
+			files: []string{"001_users.sql", "010_media.sql", "002_chirps.sql"},
+			want:  10,
+		},
+		{
+			name:    "No migrations",
+			files:   []string{},
+			wantErr: true,
+		},
+		{
+			name:    "Missing version",
+			files:   []string{"001_users.sql", "users.sql"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for _, file := range tt.files {
+				fsys[file] = &fstest.MapFile{Data: []byte("-- +goose Up\n")}
+			}
+			got, err := SchemaVersion(fsys)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SchemaVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("SchemaVersion() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
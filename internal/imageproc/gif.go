@@ -0,0 +1,55 @@
+package imageproc
+
+import "errors"
+
+var errBadGIF = errors.New("gif: malformed data")
+
+// gifFrames counts the frames of a GIF by walking its blocks, without
+// decompressing any of them.
+func gifFrames(data []byte) (int, error) {
+	// Header and logical screen descriptor.
+	const headerSize = 13
+	if len(data) < headerSize {
+		return 0, errBadGIF
+	}
+	pos := headerSize
+	if flags := data[10]; flags&0x80 != 0 {
+		pos += 3 << (flags&0x07 + 1)
+	}
+	frames := 0
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // Extension: label, then sub-blocks.
+			pos += 2
+		case 0x2C: // Image descriptor, optional color table, LZW code size.
+			if pos+10 > len(data) {
+				return 0, errBadGIF
+			}
+			frames++
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			pos++
+		case 0x3B: // Trailer.
+			return frames, nil
+		default:
+			return 0, errBadGIF
+		}
+		// Skip the data sub-blocks, each prefixed with its size, up to the
+		// empty block ending them.
+		for {
+			if pos >= len(data) {
+				return 0, errBadGIF
+			}
+			size := int(data[pos])
+			pos += size + 1
+			if size == 0 {
+				break
+			}
+		}
+	}
+	// Some encoders leave out the trailer.
+	return frames, nil
+}
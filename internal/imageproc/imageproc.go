@@ -0,0 +1,191 @@
+package imageproc
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+)
+
+// ThumbnailSizes maps thumbnail names to the longest side they're scaled to.
+var ThumbnailSizes = map[string]int{
+	"small":  150,
+	"medium": 600,
+}
+
+const (
+	jpegQuality    = 90
+	blurHashSample = 32
+)
+
+// Limits on what Process will decode, so an upload that's small on disk
+// can't claim enough pixels to exhaust memory once decoded.
+const (
+	MaxPixels = 25_000_000
+	MaxFrames = 200
+	// MaxGIFPixels caps the pixels of all of a GIF's frames together.
+	MaxGIFPixels = 100_000_000
+)
+
+var (
+	ErrUnsupported = errors.New("unsupported image type")
+	ErrTooLarge    = errors.New("image dimensions or frame count too large")
+)
+
+// Variant is an encoded rendition of an uploaded image.
+type Variant struct {
+	Name     string
+	MimeType string
+	Width    int
+	Height   int
+	Data     []byte
+}
+
+// Result is everything derived from one upload.
+type Result struct {
+	Original   Variant
+	Thumbnails []Variant
+	BlurHash   string
+}
+
+// Sniff detects the type of an image from its content, ignoring whatever the
+// client claimed. Only types we can process are reported as ok.
+func Sniff(data []byte) (string, bool) {
+	mimeType := http.DetectContentType(data)
+	switch mimeType {
+	case "image/png", "image/jpeg", "image/gif":
+		return mimeType, true
+	}
+	return mimeType, false
+}
+
+// Check reads the dimensions of an image without decoding it and fails with
+// ErrTooLarge if it's over MaxPixels or, for GIFs, has more than MaxFrames
+// frames or MaxGIFPixels pixels in all.
+func Check(data []byte) (image.Config, error) {
+	mimeType, ok := Sniff(data)
+	if !ok {
+		return image.Config{}, ErrUnsupported
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return image.Config{}, err
+	}
+	pixels := int64(cfg.Width) * int64(cfg.Height)
+	if pixels > MaxPixels {
+		return image.Config{}, ErrTooLarge
+	}
+	if mimeType == "image/gif" {
+		frames, err := gifFrames(data)
+		if err != nil {
+			return image.Config{}, err
+		}
+		if frames > MaxFrames || int64(frames)*pixels > MaxGIFPixels {
+			return image.Config{}, ErrTooLarge
+		}
+	}
+	return cfg, nil
+}
+
+// Process re-encodes an image, which drops EXIF, GPS and any other embedded
+// metadata, and derives thumbnails and a BlurHash from it. JPEG orientation
+// is applied to the pixels before the metadata holding it is dropped.
+func Process(data []byte) (*Result, error) {
+	if _, err := Check(data); err != nil {
+		return nil, err
+	}
+	mimeType, _ := Sniff(data)
+	result := &Result{}
+	var img image.Image
+	switch mimeType {
+	case "image/gif":
+		// Keep every frame so animations survive.
+		anim, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		buf := bytes.Buffer{}
+		clean := &gif.GIF{Image: anim.Image, Delay: anim.Delay, LoopCount: anim.LoopCount, Disposal: anim.Disposal, Config: anim.Config, BackgroundIndex: anim.BackgroundIndex}
+		if err = gif.EncodeAll(&buf, clean); err != nil {
+			return nil, err
+		}
+		img = anim.Image[0]
+		result.Original = Variant{Name: "original", MimeType: mimeType, Width: anim.Config.Width, Height: anim.Config.Height, Data: buf.Bytes()}
+	default:
+		decoded, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if mimeType == "image/jpeg" {
+			decoded = applyOrientation(decoded, jpegOrientation(data))
+		}
+		img = decoded
+		encoded, err := encode(img, mimeType)
+		if err != nil {
+			return nil, err
+		}
+		result.Original = Variant{Name: "original", MimeType: mimeType, Width: img.Bounds().Dx(), Height: img.Bounds().Dy(), Data: encoded}
+	}
+
+	thumbType := "image/png"
+	if mimeType == "image/jpeg" {
+		thumbType = "image/jpeg"
+	}
+	for _, name := range []string{"small", "medium"} {
+		thumb := scaleToFit(img, ThumbnailSizes[name])
+		encoded, err := encode(thumb, thumbType)
+		if err != nil {
+			return nil, err
+		}
+		result.Thumbnails = append(result.Thumbnails, Variant{
+			Name:     name,
+			MimeType: thumbType,
+			Width:    thumb.Bounds().Dx(),
+			Height:   thumb.Bounds().Dy(),
+			Data:     encoded,
+		})
+	}
+
+	xComponents, yComponents := 4, 3
+	if img.Bounds().Dy() > img.Bounds().Dx() {
+		xComponents, yComponents = 3, 4
+	}
+	result.BlurHash = BlurHash(scaleToFit(img, blurHashSample), xComponents, yComponents)
+	return result, nil
+}
+
+// scaleToFit scales img down so its longest side is at most size. Smaller
+// images are only copied.
+func scaleToFit(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+func encode(img image.Image, mimeType string) ([]byte, error) {
+	buf := bytes.Buffer{}
+	var err error
+	switch mimeType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	case "image/png":
+		err = png.Encode(&buf, img)
+	default:
+		err = ErrUnsupported
+	}
+	return buf.Bytes(), err
+}
@@ -0,0 +1,224 @@
+package imageproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func solidImage(w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// withOrientation inserts an EXIF APP1 segment carrying the given orientation
+// right after the JPEG's SOI marker.
+func withOrientation(jpegData []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	out := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(segment)+2))
+	out = append(out, segment...)
+	return append(out, jpegData[2:]...)
+}
+
+func TestSniff(t *testing.T) {
+	pngData := bytes.Buffer{}
+	png.Encode(&pngData, solidImage(2, 2, color.White))
+
+	tests := []struct {
+		name     string
+		data     []byte
+		wantType string
+		wantOK   bool
+	}{
+		{
+			name:     "PNG",
+			data:     pngData.Bytes(),
+			wantType: "image/png",
+			wantOK:   true,
+		},
+		{
+			name:     "HTML pretending to be an image",
+			data:     []byte("<html><script>alert(1)</script></html>"),
+			wantType: "text/html; charset=utf-8",
+			wantOK:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotType, gotOK := Sniff(tt.data)
+			if gotType != tt.wantType || gotOK != tt.wantOK {
+				t.Errorf("Sniff() = %v, %v, want %v, %v", gotType, gotOK, tt.wantType, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestProcessStripsEXIFAndAppliesOrientation(t *testing.T) {
+	src := bytes.Buffer{}
+	jpeg.Encode(&src, solidImage(40, 20, color.RGBA{R: 200, G: 30, B: 30, A: 255}), nil)
+	data := withOrientation(src.Bytes(), 6)
+	if jpegOrientation(data) != 6 {
+		t.Fatalf("test image orientation = %d, want 6", jpegOrientation(data))
+	}
+
+	result, err := Process(data)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if bytes.Contains(result.Original.Data, []byte("Exif")) {
+		t.Error("processed image still contains EXIF data")
+	}
+	if result.Original.Width != 20 || result.Original.Height != 40 {
+		t.Errorf("processed size = %dx%d, want 20x40", result.Original.Width, result.Original.Height)
+	}
+	if result.Original.MimeType != "image/jpeg" {
+		t.Errorf("processed type = %v, want image/jpeg", result.Original.MimeType)
+	}
+}
+
+func TestProcessThumbnails(t *testing.T) {
+	src := bytes.Buffer{}
+	png.Encode(&src, solidImage(1200, 300, color.White))
+
+	result, err := Process(src.Bytes())
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	want := map[string][2]int{"small": {150, 37}, "medium": {600, 150}}
+	if len(result.Thumbnails) != len(want) {
+		t.Fatalf("got %d thumbnails, want %d", len(result.Thumbnails), len(want))
+	}
+	for _, thumb := range result.Thumbnails {
+		size := want[thumb.Name]
+		if thumb.Width != size[0] || thumb.Height != size[1] {
+			t.Errorf("%s thumbnail = %dx%d, want %dx%d", thumb.Name, thumb.Width, thumb.Height, size[0], size[1])
+		}
+		if _, err := png.Decode(bytes.NewReader(thumb.Data)); err != nil {
+			t.Errorf("%s thumbnail doesn't decode: %v", thumb.Name, err)
+		}
+	}
+}
+
+func TestProcessRejectsUnsupported(t *testing.T) {
+	if _, err := Process([]byte("GIF89a but not really")); err == nil {
+		t.Error("Process() of a broken GIF succeeded")
+	}
+	if _, err := Process([]byte("plain text")); err != ErrUnsupported {
+		t.Errorf("Process() error = %v, want ErrUnsupported", err)
+	}
+}
+
+// withDimensions rewrites the size in a PNG's header, leaving its pixel data
+// as it was.
+func withDimensions(pngData []byte, w, h uint32) []byte {
+	out := bytes.Clone(pngData)
+	binary.BigEndian.PutUint32(out[16:], w)
+	binary.BigEndian.PutUint32(out[20:], h)
+	binary.BigEndian.PutUint32(out[29:], crc32.ChecksumIEEE(out[12:29]))
+	return out
+}
+
+func animation(frames, w, h int) []byte {
+	anim := &gif.GIF{Config: image.Config{Width: w, Height: h}}
+	for range frames {
+		anim.Image = append(anim.Image, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.White, color.Black}))
+		anim.Delay = append(anim.Delay, 10)
+	}
+	buf := bytes.Buffer{}
+	gif.EncodeAll(&buf, anim)
+	return buf.Bytes()
+}
+
+func TestCheck(t *testing.T) {
+	small := bytes.Buffer{}
+	png.Encode(&small, solidImage(10, 10, color.White))
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{
+			name: "Small PNG",
+			data: small.Bytes(),
+		},
+		{
+			name:    "PNG claiming huge dimensions",
+			data:    withDimensions(small.Bytes(), 50000, 50000),
+			wantErr: ErrTooLarge,
+		},
+		{
+			name: "Short animation",
+			data: animation(10, 100, 100),
+		},
+		{
+			name:    "Too many frames",
+			data:    animation(MaxFrames+1, 1, 1),
+			wantErr: ErrTooLarge,
+		},
+		{
+			name:    "Too many pixels across frames",
+			data:    animation(5, 5000, 5000),
+			wantErr: ErrTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Check(tt.data); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Check() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBlurHash(t *testing.T) {
+	hash := BlurHash(solidImage(8, 8, color.White), 4, 3)
+	if len(hash) != 28 {
+		t.Fatalf("len(BlurHash()) = %d, want 28", len(hash))
+	}
+	if hash[0] != base83Chars[3+2*9] {
+		t.Errorf("size flag = %q, want %q", hash[0], base83Chars[3+2*9])
+	}
+	if dc := hash[2:6]; dc != encode83(0xFFFFFF, 4) {
+		t.Errorf("DC component = %q, want white %q", dc, encode83(0xFFFFFF, 4))
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	src.SetRGBA(0, 0, red)
+	src.SetRGBA(1, 0, blue)
+
+	// Orientation 6 means the camera was rotated; displaying it upright
+	// turns the row into a column with the left pixel on top.
+	got := applyOrientation(src, 6).(*image.RGBA)
+	if got.Bounds().Dx() != 1 || got.Bounds().Dy() != 2 {
+		t.Fatalf("rotated size = %v, want 1x2", got.Bounds())
+	}
+	if got.RGBAAt(0, 0) != red || got.RGBAAt(0, 1) != blue {
+		t.Errorf("rotated pixels = %v, %v, want red, blue", got.RGBAAt(0, 0), got.RGBAAt(0, 1))
+	}
+}
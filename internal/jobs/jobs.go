@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Job statuses. Dead jobs have failed permanently or run out of attempts
+// and stay put until they're retried by hand.
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusDead      = "dead"
+)
+
+const (
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+)
+
+var ErrUnknownKind = errors.New("no handler registered for job kind")
+
+// Handler runs a job from its raw JSON payload.
+type Handler func(ctx context.Context, payload json.RawMessage) error
+
+// Registry maps job kinds to their handlers.
+type Registry struct {
+	handlers map[string]Handler
+}
+
+func NewRegistry() *Registry {
+	return &Registry{handlers: map[string]Handler{}}
+}
+
+// Register adds the handler for kind. Payloads are decoded into T before
+// handle is called; ones that can't be decoded fail permanently. Kinds can
+// only be registered once.
+func Register[T any](r *Registry, kind string, handle func(ctx context.Context, payload T) error) {
+	if _, ok := r.handlers[kind]; ok {
+		panic("jobs: handler for " + kind + " registered twice")
+	}
+	r.handlers[kind] = func(ctx context.Context, raw json.RawMessage) error {
+		var payload T
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			return Permanent(fmt.Errorf("decoding %s payload: %w", kind, err))
+		}
+		return handle(ctx, payload)
+	}
+}
+
+// Run runs a job of the given kind. A panicking handler counts as a failed
+// attempt rather than taking the worker down.
+func (r *Registry) Run(ctx context.Context, kind string, payload json.RawMessage) (err error) {
+	handle, ok := r.handlers[kind]
+	if !ok {
+		return Permanent(fmt.Errorf("%w: %s", ErrUnknownKind, kind))
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+	}()
+	return handle(ctx, payload)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying: the job goes straight to dead.
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+func IsPermanent(err error) bool {
+	return errors.As(err, new(permanentError))
+}
+
+// Backoff is how long to wait before retrying a job that has failed
+// attempts times: 10s doubling each time, up to an hour.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+	backoff := baseBackoff
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
@@ -0,0 +1,136 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+type greeting struct {
+	Name string `json:"name"`
+}
+
+func TestRun(t *testing.T) {
+	errFailed := errors.New("failed")
+	r := NewRegistry()
+	var got string
+	Register(r, "greet", func(ctx context.Context, g greeting) error {
+		got = g.Name
+		return nil
+	})
+	Register(r, "fail", func(ctx context.Context, g greeting) error {
+		return errFailed
+	})
+	Register(r, "panic", func(ctx context.Context, g greeting) error {
+		panic("boom")
+	})
+
+	tests := []struct {
+		name          string
+		kind          string
+		payload       string
+		wantErr       bool
+		wantPermanent bool
+	}{
+		{
+			name:    "Runs handler",
+			kind:    "greet",
+			payload: `{"name": "chirpy"}`,
+			wantErr: false,
+		},
+		{
+			name:    "Handler error",
+			kind:    "fail",
+			payload: `{}`,
+			wantErr: true,
+		},
+		{
+			name:    "Handler panic",
+			kind:    "panic",
+			payload: `{}`,
+			wantErr: true,
+		},
+		{
+			name:          "Unknown kind",
+			kind:          "missing",
+			payload:       `{}`,
+			wantErr:       true,
+			wantPermanent: true,
+		},
+		{
+			name:          "Bad payload",
+			kind:          "greet",
+			payload:       `{"name": 1}`,
+			wantErr:       true,
+			wantPermanent: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.Run(context.Background(), tt.kind, json.RawMessage(tt.payload))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if IsPermanent(err) != tt.wantPermanent {
+				t.Errorf("IsPermanent() = %v, want %v", IsPermanent(err), tt.wantPermanent)
+			}
+		})
+	}
+	if got != "chirpy" {
+		t.Errorf("handler got name %q, want %q", got, "chirpy")
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	base := errors.New("bad input")
+	err := Permanent(base)
+	if !IsPermanent(err) {
+		t.Error("IsPermanent() = false for a permanent error")
+	}
+	if !errors.Is(err, base) {
+		t.Error("permanent error doesn't wrap the original")
+	}
+	if IsPermanent(base) {
+		t.Error("IsPermanent() = true for a plain error")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		want     time.Duration
+	}{
+		{
+			name:     "No attempts",
+			attempts: 0,
+			want:     0,
+		},
+		{
+			name:     "First failure",
+			attempts: 1,
+			want:     10 * time.Second,
+		},
+		{
+			name:     "Fourth failure",
+			attempts: 4,
+			want:     80 * time.Second,
+		},
+		{
+			name:     "Capped",
+			attempts: 30,
+			want:     time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Backoff(tt.attempts); got != tt.want {
+				t.Errorf("Backoff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
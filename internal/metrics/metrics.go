@@ -0,0 +1,210 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "chirpy"
+
+// unmatchedRoute labels requests no route matched, so scanners can't blow up
+// the number of series.
+const unmatchedRoute = "unmatched"
+
+// Metrics holds Chirpy's collectors and the registry they're exposed
+// through.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+	inFlight     prometheus.Gauge
+
+	FileserverHits       prometheus.Counter
+	fileserverHitsReset  prometheus.Gauge
+	ChirpsCreated        prometheus.Counter
+	Logins               *prometheus.CounterVec
+	WebhookEvents        *prometheus.CounterVec
+	WebhookDeliveries    *prometheus.CounterVec
+	RefreshTokenGCRuns   prometheus.Counter
+	RefreshTokensRemoved prometheus.Counter
+	RefreshTokenGCLast   prometheus.Gauge
+}
+
+// New registers Chirpy's metrics, along with Go runtime, process and db
+// connection pool stats, on a fresh registry.
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to serve HTTP requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_response_size_bytes",
+			Help:      "Size of HTTP response bodies.",
+			Buckets:   prometheus.ExponentialBuckets(64, 4, 8),
+		}, []string{"route", "method"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests being served.",
+		}),
+		FileserverHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "fileserver_hits_total",
+			Help:      "Requests for the web app.",
+		}),
+		fileserverHitsReset: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "fileserver_hits_at_reset",
+			Help:      "Requests for the web app when the admin count was last reset.",
+		}),
+		ChirpsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "chirps_created_total",
+			Help:      "Chirps created, directly or from drafts.",
+		}),
+		Logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Login attempts by result.",
+		}, []string{"result"}),
+		WebhookEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_events_total",
+			Help:      "Incoming webhook events by provider and outcome.",
+		}, []string{"provider", "status"}),
+		WebhookDeliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_delivery_attempts_total",
+			Help:      "Outgoing webhook delivery attempts by resulting delivery status.",
+		}, []string{"status"}),
+		RefreshTokenGCRuns: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "refresh_token_gc_runs_total",
+			Help:      "Runs of stale refresh token collection.",
+		}),
+		RefreshTokensRemoved: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "refresh_tokens_removed_total",
+			Help:      "Stale refresh tokens deleted.",
+		}),
+		RefreshTokenGCLast: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "refresh_token_gc_last_run_timestamp_seconds",
+			Help:      "Unix time stale refresh tokens were last collected.",
+		}),
+	}
+	m.Registry.MustRegister(
+		m.requests, m.duration, m.responseSize, m.inFlight,
+		m.FileserverHits, m.fileserverHitsReset, m.ChirpsCreated, m.Logins, m.WebhookEvents, m.WebhookDeliveries,
+		m.RefreshTokenGCRuns, m.RefreshTokensRemoved, m.RefreshTokenGCLast,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	if db != nil {
+		m.Registry.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+	}
+	return m
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
+
+// Middleware records every request served by next, which must be or wrap a
+// ServeMux: requests are labelled with the pattern of the route that served
+// them, not their path.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+		start := time.Now()
+		rec := &recorder{ResponseWriter: w, status: 200}
+		next.ServeHTTP(rec, req)
+		route := req.Pattern
+		if route == "" {
+			route = unmatchedRoute
+		}
+		m.requests.WithLabelValues(route, req.Method, strconv.Itoa(rec.status)).Inc()
+		m.duration.WithLabelValues(route, req.Method).Observe(time.Since(start).Seconds())
+		m.responseSize.WithLabelValues(route, req.Method).Observe(float64(rec.size))
+	})
+}
+
+// Value returns the total of the named metric across its series, read back
+// from the registry. It's 0 for metrics that don't exist or aren't counters
+// or gauges.
+func (m *Metrics) Value(name string) float64 {
+	families, err := m.Registry.Gather()
+	if err != nil {
+		return 0
+	}
+	var total float64
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			total += metric.GetCounter().GetValue() + metric.GetGauge().GetValue()
+		}
+	}
+	return total
+}
+
+// Hits returns the requests for the web app since the last ResetHits.
+func (m *Metrics) Hits() float64 {
+	return m.Value(namespace+"_fileserver_hits_total") - m.Value(namespace+"_fileserver_hits_at_reset")
+}
+
+// ResetHits zeroes the count Hits returns. The counter itself keeps going,
+// since Prometheus counters only reset when the process restarts.
+func (m *Metrics) ResetHits() {
+	m.fileserverHitsReset.Set(m.Value(namespace + "_fileserver_hits_total"))
+}
+
+// recorder captures the status code and body size of a response.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	size        int
+	wroteHeader bool
+}
+
+func (r *recorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.size += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, which SSE
+// flushing, deadline changes and WebSocket hijacking depend on.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
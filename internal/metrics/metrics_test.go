@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/chirps/{chirpID}", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(404)
+		w.Write([]byte(`{"error":"Chirp not found"}`))
+	})
+	tests := []struct {
+		name   string
+		path   string
+		series string
+	}{
+		{
+			name:   "Matched route",
+			path:   "/api/chirps/7b1d7ac0-0a5c-4f3b-9d4b-3f8f0f1e2a6c",
+			series: `chirpy_http_requests_total{code="404",method="GET",route="GET /api/chirps/{chirpID}"} 1`,
+		},
+		{
+			name:   "Unmatched route",
+			path:   "/wp-login.php",
+			series: `chirpy_http_requests_total{code="404",method="GET",route="unmatched"} 1`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(nil)
+			handler := m.Middleware(mux)
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+
+			rec := httptest.NewRecorder()
+			m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+			if !strings.Contains(rec.Body.String(), tt.series) {
+				t.Errorf("metrics don't contain %s:\n%s", tt.series, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestValue(t *testing.T) {
+	m := New(nil)
+	m.FileserverHits.Add(3)
+	m.Logins.WithLabelValues("success").Add(2)
+	m.Logins.WithLabelValues("failure").Inc()
+	tests := []struct {
+		name   string
+		metric string
+		want   float64
+	}{
+		{name: "Counter", metric: "chirpy_fileserver_hits_total", want: 3},
+		{name: "Summed across labels", metric: "chirpy_logins_total", want: 3},
+		{name: "Unknown metric", metric: "chirpy_nonexistent_total", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Value(tt.metric); got != tt.want {
+				t.Errorf("Value(%q) = %v, want %v", tt.metric, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHits(t *testing.T) {
+	m := New(nil)
+	m.FileserverHits.Add(3)
+	if got := m.Hits(); got != 3 {
+		t.Fatalf("Hits() = %v, want 3", got)
+	}
+	m.ResetHits()
+	if got := m.Hits(); got != 0 {
+		t.Errorf("Hits() after ResetHits() = %v, want 0", got)
+	}
+	m.FileserverHits.Add(2)
+	if got := m.Hits(); got != 2 {
+		t.Errorf("Hits() = %v, want 2", got)
+	}
+	if got := m.Value("chirpy_fileserver_hits_total"); got != 5 {
+		t.Errorf("counter = %v, want 5 after a reset", got)
+	}
+}
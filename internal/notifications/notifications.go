@@ -0,0 +1,112 @@
+package notifications
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TypeFollow            = "follow"
+	TypeLike              = "like"
+	TypeReply             = "reply"
+	TypeMention           = "mention"
+	TypeChirpyRedUpgraded = "chirpy_red.upgraded"
+)
+
+// Notification is a single stored notification.
+type Notification struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Type      string
+	ActorID   uuid.NullUUID
+	ChirpID   uuid.NullUUID
+	Read      bool
+}
+
+// Group is one or more notifications shown to the user as a single entry,
+// e.g. "3 people liked your chirp".
+type Group struct {
+	Type            string      `json:"type"`
+	Message         string      `json:"message"`
+	ChirpID         *uuid.UUID  `json:"chirp_id"`
+	ActorIDs        []uuid.UUID `json:"actor_ids"`
+	NotificationIDs []uuid.UUID `json:"notification_ids"`
+	CreatedAt       time.Time   `json:"created_at"`
+	Read            bool        `json:"read"`
+}
+
+// groupable reports whether notifications of this type about the same chirp
+// collapse into one entry. Replies and mentions each carry their own chirp, so
+// they are always shown individually.
+func groupable(notificationType string) bool {
+	return notificationType == TypeLike || notificationType == TypeFollow
+}
+
+// GroupNotifications collapses notifications, newest first, into groups. A
+// group takes the position of its newest notification and is read only once
+// every notification in it is.
+func GroupNotifications(ns []Notification) []Group {
+	groups := []Group{}
+	index := map[string]int{}
+	for _, n := range ns {
+		key := ""
+		if groupable(n.Type) {
+			key = n.Type + "|" + n.ChirpID.UUID.String()
+			if i, ok := index[key]; ok {
+				g := &groups[i]
+				g.NotificationIDs = append(g.NotificationIDs, n.ID)
+				if n.ActorID.Valid && !slices.Contains(g.ActorIDs, n.ActorID.UUID) {
+					g.ActorIDs = append(g.ActorIDs, n.ActorID.UUID)
+				}
+				g.Read = g.Read && n.Read
+				g.Message = Message(g.Type, len(g.ActorIDs))
+				continue
+			}
+		}
+		g := Group{
+			Type:            n.Type,
+			ActorIDs:        []uuid.UUID{},
+			NotificationIDs: []uuid.UUID{n.ID},
+			CreatedAt:       n.CreatedAt,
+			Read:            n.Read,
+		}
+		if n.ChirpID.Valid {
+			chirpID := n.ChirpID.UUID
+			g.ChirpID = &chirpID
+		}
+		if n.ActorID.Valid {
+			g.ActorIDs = append(g.ActorIDs, n.ActorID.UUID)
+		}
+		g.Message = Message(g.Type, len(g.ActorIDs))
+		groups = append(groups, g)
+		if key != "" {
+			index[key] = len(groups) - 1
+		}
+	}
+	return groups
+}
+
+// Message renders the text shown for a group of notifications.
+func Message(notificationType string, actors int) string {
+	who := "Someone"
+	if actors > 1 {
+		who = fmt.Sprintf("%d people", actors)
+	}
+	switch notificationType {
+	case TypeFollow:
+		return who + " followed you"
+	case TypeLike:
+		return who + " liked your chirp"
+	case TypeReply:
+		return who + " replied to your chirp"
+	case TypeMention:
+		return who + " mentioned you"
+	case TypeChirpyRedUpgraded:
+		return "Your Chirpy Red upgrade went through"
+	default:
+		return "You have a new notification"
+	}
+}
@@ -0,0 +1,103 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestGroupNotifications(t *testing.T) {
+	chirp1 := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	chirp2 := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	alice := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	bob := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	carol := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	now := time.Now()
+
+	ns := []Notification{
+		{ID: uuid.New(), CreatedAt: now, Type: TypeLike, ActorID: alice, ChirpID: chirp1},
+		{ID: uuid.New(), CreatedAt: now.Add(-time.Minute), Type: TypeReply, ActorID: bob, ChirpID: chirp1},
+		{ID: uuid.New(), CreatedAt: now.Add(-2 * time.Minute), Type: TypeLike, ActorID: bob, ChirpID: chirp1, Read: true},
+		{ID: uuid.New(), CreatedAt: now.Add(-3 * time.Minute), Type: TypeLike, ActorID: carol, ChirpID: chirp2},
+		{ID: uuid.New(), CreatedAt: now.Add(-4 * time.Minute), Type: TypeLike, ActorID: carol, ChirpID: chirp1},
+		{ID: uuid.New(), CreatedAt: now.Add(-5 * time.Minute), Type: TypeChirpyRedUpgraded},
+	}
+
+	groups := GroupNotifications(ns)
+
+	tests := []struct {
+		name          string
+		group         Group
+		wantType      string
+		wantMessage   string
+		wantActors    int
+		wantIDs       int
+		wantRead      bool
+		wantCreatedAt time.Time
+	}{
+		{
+			name:          "Likes on the same chirp are grouped",
+			group:         groups[0],
+			wantType:      TypeLike,
+			wantMessage:   "3 people liked your chirp",
+			wantActors:    3,
+			wantIDs:       3,
+			wantRead:      false,
+			wantCreatedAt: now,
+		},
+		{
+			name:          "Replies are not grouped",
+			group:         groups[1],
+			wantType:      TypeReply,
+			wantMessage:   "Someone replied to your chirp",
+			wantActors:    1,
+			wantIDs:       1,
+			wantCreatedAt: now.Add(-time.Minute),
+		},
+		{
+			name:          "Likes on another chirp are separate",
+			group:         groups[2],
+			wantType:      TypeLike,
+			wantMessage:   "Someone liked your chirp",
+			wantActors:    1,
+			wantIDs:       1,
+			wantCreatedAt: now.Add(-3 * time.Minute),
+		},
+		{
+			name:          "Notifications without an actor",
+			group:         groups[3],
+			wantType:      TypeChirpyRedUpgraded,
+			wantMessage:   "Your Chirpy Red upgrade went through",
+			wantActors:    0,
+			wantIDs:       1,
+			wantCreatedAt: now.Add(-5 * time.Minute),
+		},
+	}
+
+	if len(groups) != len(tests) {
+		t.Fatalf("GroupNotifications() returned %d groups, want %d", len(groups), len(tests))
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.group.Type != tt.wantType {
+				t.Errorf("Type = %v, want %v", tt.group.Type, tt.wantType)
+			}
+			if tt.group.Message != tt.wantMessage {
+				t.Errorf("Message = %q, want %q", tt.group.Message, tt.wantMessage)
+			}
+			if len(tt.group.ActorIDs) != tt.wantActors {
+				t.Errorf("len(ActorIDs) = %v, want %v", len(tt.group.ActorIDs), tt.wantActors)
+			}
+			if len(tt.group.NotificationIDs) != tt.wantIDs {
+				t.Errorf("len(NotificationIDs) = %v, want %v", len(tt.group.NotificationIDs), tt.wantIDs)
+			}
+			if tt.group.Read != tt.wantRead {
+				t.Errorf("Read = %v, want %v", tt.group.Read, tt.wantRead)
+			}
+			if !tt.group.CreatedAt.Equal(tt.wantCreatedAt) {
+				t.Errorf("CreatedAt = %v, want %v", tt.group.CreatedAt, tt.wantCreatedAt)
+			}
+		})
+	}
+}
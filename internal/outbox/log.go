@@ -0,0 +1,24 @@
+package outbox
+
+import (
+	"context"
+	"log"
+)
+
+// LogSink writes messages to a logger, which is mostly useful in
+// development. Only the ID, type and aggregate are logged, since the data
+// can hold private chirps.
+type LogSink struct {
+	logger *log.Logger
+}
+
+func NewLogSink(logger *log.Logger) *LogSink {
+	return &LogSink{logger: logger}
+}
+
+func (s *LogSink) Name() string { return "log" }
+
+func (s *LogSink) Publish(ctx context.Context, m Message) error {
+	s.logger.Printf("outbox %s: %s %s/%s", m.ID, m.Type, m.AggregateType, m.AggregateID)
+	return nil
+}
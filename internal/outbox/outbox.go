@@ -0,0 +1,28 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is an event recorded in the outbox along with the change it
+// describes. Sinks may see a message more than once and should use ID to
+// deduplicate.
+type Message struct {
+	ID            uuid.UUID       `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   uuid.UUID       `json:"aggregate_id"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// Sink publishes outbox messages to another system. Publish returns once the
+// message has been accepted; an error means it will be published again.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, m Message) error
+}
@@ -0,0 +1,206 @@
+package outbox
+
+import (
+	"bufio"
+	"bytes"
+	"chirpy/internal/auth"
+	"chirpy/internal/webhooks"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func testMessage() Message {
+	return Message{
+		ID:            uuid.New(),
+		Type:          "chirp.created",
+		AggregateType: "chirp",
+		AggregateID:   uuid.New(),
+		CreatedAt:     time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+		Data:          json.RawMessage(`{"body":"hello"}`),
+	}
+}
+
+func TestLogSink(t *testing.T) {
+	var buf bytes.Buffer
+	m := testMessage()
+	if err := NewLogSink(log.New(&buf, "", 0)).Publish(context.Background(), m); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), m.ID.String()) || !strings.Contains(buf.String(), "chirp.created") {
+		t.Errorf("log output %q is missing the message", buf.String())
+	}
+	if strings.Contains(buf.String(), string(m.Data)) {
+		t.Errorf("log output %q contains the message data", buf.String())
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{
+			name:    "Accepted",
+			status:  204,
+			wantErr: false,
+		},
+		{
+			name:    "Rejected",
+			status:  500,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testMessage()
+			badSignature := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				body, _ := io.ReadAll(req.Body)
+				unix, _ := strconv.ParseInt(req.Header.Get(webhooks.TimestampHeader), 10, 64)
+				if req.Header.Get(webhooks.SignatureHeader) != auth.SignWebhook("secret", time.Unix(unix, 0), body) {
+					badSignature = true
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+			err := NewWebhookSink(server.URL, "secret", server.Client()).Publish(context.Background(), m)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Publish() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if badSignature {
+				t.Error("request signature doesn't match")
+			}
+		})
+	}
+}
+
+// fakeNATS accepts one connection, speaks just enough of the protocol and
+// sends the subjects and payloads it receives on got. With reject set it
+// answers PUBs with -ERR.
+func fakeNATS(t *testing.T, reject bool) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	got := make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("INFO {\"server_id\":\"fake\"}\r\n"))
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch fields[0] {
+			case "PUB":
+				size, _ := strconv.Atoi(fields[2])
+				payload := make([]byte, size+2)
+				if _, err = io.ReadFull(r, payload); err != nil {
+					return
+				}
+				if reject {
+					conn.Write([]byte("-ERR 'Permissions Violation'\r\n"))
+					continue
+				}
+				got <- fields[1] + " " + string(payload[:size])
+			case "PING":
+				conn.Write([]byte("PONG\r\n"))
+			}
+		}
+	}()
+	return "nats://" + ln.Addr().String(), got
+}
+
+func TestNATSSink(t *testing.T) {
+	addr, got := fakeNATS(t, false)
+	sink, err := NewNATSSink(addr, "chirpy")
+	if err != nil {
+		t.Fatalf("NewNATSSink() error = %v", err)
+	}
+	defer sink.Close()
+	m := testMessage()
+	for range 2 {
+		if err = sink.Publish(context.Background(), m); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+		select {
+		case line := <-got:
+			if !strings.HasPrefix(line, "chirpy.chirp.created ") || !strings.Contains(line, m.ID.String()) {
+				t.Errorf("server got %q", line)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("server didn't receive the message")
+		}
+	}
+}
+
+func TestNATSSinkRejected(t *testing.T) {
+	addr, _ := fakeNATS(t, true)
+	sink, err := NewNATSSink(addr, "chirpy")
+	if err != nil {
+		t.Fatalf("NewNATSSink() error = %v", err)
+	}
+	defer sink.Close()
+	if err = sink.Publish(context.Background(), testMessage()); err == nil {
+		t.Error("Publish() succeeded after -ERR")
+	}
+}
+
+func TestNewNATSSink(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{
+			name:    "NATS URL",
+			url:     "nats://localhost:4222",
+			wantErr: false,
+		},
+		{
+			name:    "Host and port",
+			url:     "localhost:4222",
+			wantErr: false,
+		},
+		{
+			name:    "Wrong scheme",
+			url:     "http://localhost:4222",
+			wantErr: true,
+		},
+		{
+			name:    "Missing port",
+			url:     "localhost",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewNATSSink(tt.url, "chirpy")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewNATSSink() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
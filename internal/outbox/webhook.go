@@ -0,0 +1,57 @@
+package outbox
+
+import (
+	"bytes"
+	"chirpy/internal/auth"
+	"chirpy/internal/webhooks"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// WebhookSink posts each message as JSON to a URL, signed like Chirpy's
+// outgoing webhooks when a secret is set.
+type WebhookSink struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewWebhookSink(url, secret string, client *http.Client) *WebhookSink {
+	return &WebhookSink{url: url, secret: secret, client: client}
+}
+
+func (s *WebhookSink) Name() string { return "webhook" }
+
+func (s *WebhookSink) Publish(ctx context.Context, m Message) error {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhooks.EventHeader, m.Type)
+	req.Header.Set(webhooks.DeliveryHeader, m.ID.String())
+	if s.secret != "" {
+		req.Header.Set(webhooks.TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+		req.Header.Set(webhooks.SignatureHeader, auth.SignWebhook(s.secret, now, body))
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook sink responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := Cursor{
+		CreatedAt: time.Date(2025, 6, 1, 12, 30, 45, 123456000, time.UTC),
+		ID:        uuid.New(),
+	}
+	got, err := Decode(want.Encode())
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID {
+		t.Errorf("Decode() = %v, want %v", got, want)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		cursor  string
+		wantErr bool
+	}{
+		{
+			name:    "Valid cursor",
+			cursor:  Cursor{CreatedAt: time.Now(), ID: uuid.New()}.Encode(),
+			wantErr: false,
+		},
+		{
+			name:    "Not base64",
+			cursor:  "not a cursor!",
+			wantErr: true,
+		},
+		{
+			name:    "Missing separator",
+			cursor:  "aGVsbG8",
+			wantErr: true,
+		},
+		{
+			name:    "Empty cursor",
+			cursor:  "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(tt.cursor)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   string
+		want    int
+		wantErr bool
+	}{
+		{
+			name:  "Default",
+			limit: "",
+			want:  DefaultLimit,
+		},
+		{
+			name:  "Explicit",
+			limit: "5",
+			want:  5,
+		},
+		{
+			name:  "Capped",
+			limit: "1000",
+			want:  MaxLimit,
+		},
+		{
+			name:    "Negative",
+			limit:   "-1",
+			wantErr: true,
+		},
+		{
+			name:    "Not a number",
+			limit:   "ten",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Limit(tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Limit() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Limit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package polls
+
+import (
+	"chirpy/internal/chirptext"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	MinOptions      = 2
+	MaxOptions      = 4
+	MaxOptionLength = 50
+	MinDuration     = 5 * time.Minute
+	MaxDuration     = 7 * 24 * time.Hour
+)
+
+// Validate checks the options and expiry of a new poll created at now.
+func Validate(options []string, expiresAt, now time.Time) error {
+	if len(options) < MinOptions || len(options) > MaxOptions {
+		return fmt.Errorf("a poll must have between %d and %d options", MinOptions, MaxOptions)
+	}
+	seen := map[string]bool{}
+	for _, option := range options {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			return errors.New("poll options can't be empty")
+		}
+		if chirptext.Length(option) > MaxOptionLength {
+			return fmt.Errorf("poll options can be at most %d characters long", MaxOptionLength)
+		}
+		if seen[strings.ToLower(option)] {
+			return errors.New("poll options must be unique")
+		}
+		seen[strings.ToLower(option)] = true
+	}
+	duration := expiresAt.Sub(now)
+	if duration < MinDuration || duration > MaxDuration {
+		return fmt.Errorf("a poll must run for between %s and %s", MinDuration, MaxDuration)
+	}
+	return nil
+}
+
+// Percentages turns vote counts into whole percentages that add up to 100,
+// handing the points lost to rounding down to the largest remainders. With no
+// votes every option gets 0.
+func Percentages(votes []int64) []int {
+	percentages := make([]int, len(votes))
+	var total int64
+	for _, v := range votes {
+		total += v
+	}
+	if total == 0 {
+		return percentages
+	}
+	remaining := 100
+	order := make([]int, len(votes))
+	for i, v := range votes {
+		percentages[i] = int(v * 100 / total)
+		remaining -= percentages[i]
+		order[i] = i
+	}
+	// Ties go to the earlier option so results are stable.
+	slices.SortStableFunc(order, func(a, b int) int {
+		ra, rb := votes[a]*100%total, votes[b]*100%total
+		switch {
+		case ra > rb:
+			return -1
+		case ra < rb:
+			return 1
+		}
+		return 0
+	})
+	for _, i := range order[:remaining] {
+		percentages[i]++
+	}
+	return percentages
+}
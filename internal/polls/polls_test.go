@@ -0,0 +1,114 @@
+package polls
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		options   []string
+		expiresAt time.Time
+		wantErr   bool
+	}{
+		{
+			name:      "Valid poll",
+			options:   []string{"Yes", "No"},
+			expiresAt: now.Add(24 * time.Hour),
+			wantErr:   false,
+		},
+		{
+			name:      "Too few options",
+			options:   []string{"Yes"},
+			expiresAt: now.Add(24 * time.Hour),
+			wantErr:   true,
+		},
+		{
+			name:      "Too many options",
+			options:   []string{"a", "b", "c", "d", "e"},
+			expiresAt: now.Add(24 * time.Hour),
+			wantErr:   true,
+		},
+		{
+			name:      "Blank option",
+			options:   []string{"Yes", "  "},
+			expiresAt: now.Add(24 * time.Hour),
+			wantErr:   true,
+		},
+		{
+			name:      "Duplicate options",
+			options:   []string{"Yes", "yes"},
+			expiresAt: now.Add(24 * time.Hour),
+			wantErr:   true,
+		},
+		{
+			name:      "Option too long",
+			options:   []string{"Yes", strings.Repeat("a", MaxOptionLength+1)},
+			expiresAt: now.Add(24 * time.Hour),
+			wantErr:   true,
+		},
+		{
+			name:      "Expires too soon",
+			options:   []string{"Yes", "No"},
+			expiresAt: now.Add(time.Minute),
+			wantErr:   true,
+		},
+		{
+			name:      "Expires too late",
+			options:   []string{"Yes", "No"},
+			expiresAt: now.Add(MaxDuration + time.Second),
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.options, tt.expiresAt, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPercentages(t *testing.T) {
+	tests := []struct {
+		name  string
+		votes []int64
+		want  []int
+	}{
+		{
+			name:  "No votes",
+			votes: []int64{0, 0},
+			want:  []int{0, 0},
+		},
+		{
+			name:  "Even split",
+			votes: []int64{1, 1},
+			want:  []int{50, 50},
+		},
+		{
+			name:  "Thirds",
+			votes: []int64{1, 1, 1},
+			want:  []int{34, 33, 33},
+		},
+		{
+			name:  "Largest remainder wins",
+			votes: []int64{1, 2, 4},
+			want:  []int{14, 29, 57},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Percentages(tt.votes)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Percentages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
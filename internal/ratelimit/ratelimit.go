@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// maxIdleBuckets bounds how many buckets are kept before full ones, which
+// hold no state worth keeping, are dropped.
+const maxIdleBuckets = 10000
+
+// Limiter is an in-memory token bucket limiter keyed by caller. Each key's
+// bucket holds up to a minute's worth of requests and refills continuously,
+// so short bursts are allowed as long as the average stays under the limit.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens    float64
+	last      time.Time
+	perMinute int
+}
+
+func New() *Limiter {
+	return &Limiter{buckets: map[string]*bucket{}}
+}
+
+// Allow takes a token from key's bucket for a limit of perMinute requests.
+// When the bucket is empty it returns false and how long until the next
+// token.
+func (l *Limiter) Allow(key string, perMinute int, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	capacity := float64(perMinute)
+	rate := capacity / time.Minute.Seconds()
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxIdleBuckets {
+			l.prune(now)
+		}
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(capacity, b.tokens+elapsed.Seconds()*rate)
+		b.last = now
+	}
+	// A lowered limit, say after a downgrade, applies right away.
+	b.tokens = min(b.tokens, capacity)
+	b.perMinute = perMinute
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	return false, wait
+}
+
+func (l *Limiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		capacity := float64(b.perMinute)
+		if b.tokens+now.Sub(b.last).Minutes()*capacity >= capacity {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		perMinute int
+		requests  []time.Duration
+		wantLast  bool
+		wantWait  time.Duration
+	}{
+		{
+			name:      "Under the limit",
+			perMinute: 3,
+			requests:  []time.Duration{0, 0, 0},
+			wantLast:  true,
+		},
+		{
+			name:      "Burst over the limit",
+			perMinute: 3,
+			requests:  []time.Duration{0, 0, 0, 0},
+			wantLast:  false,
+			wantWait:  20 * time.Second,
+		},
+		{
+			name:      "Refilled after waiting",
+			perMinute: 3,
+			requests:  []time.Duration{0, 0, 0, 20 * time.Second},
+			wantLast:  true,
+		},
+		{
+			name:      "Partly refilled",
+			perMinute: 3,
+			requests:  []time.Duration{0, 0, 0, 10 * time.Second},
+			wantLast:  false,
+			wantWait:  10 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New()
+			var allowed bool
+			var wait time.Duration
+			for _, offset := range tt.requests {
+				allowed, wait = l.Allow("user", tt.perMinute, now.Add(offset))
+			}
+			if allowed != tt.wantLast {
+				t.Errorf("Allow() = %v, want %v", allowed, tt.wantLast)
+			}
+			if wait != tt.wantWait {
+				t.Errorf("Allow() wait = %v, want %v", wait, tt.wantWait)
+			}
+		})
+	}
+}
+
+func TestAllowKeysAreIndependent(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New()
+	if ok, _ := l.Allow("a", 1, now); !ok {
+		t.Fatal("first request for a was limited")
+	}
+	if ok, _ := l.Allow("b", 1, now); !ok {
+		t.Error("request for b was limited by a's bucket")
+	}
+	if ok, _ := l.Allow("a", 1, now); ok {
+		t.Error("second request for a was allowed")
+	}
+}
+
+func TestAllowLoweredLimit(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New()
+	l.Allow("user", 30, now)
+	if ok, _ := l.Allow("user", 1, now); !ok {
+		t.Fatal("first request under the lowered limit was limited")
+	}
+	if ok, _ := l.Allow("user", 1, now); ok {
+		t.Error("lowered limit wasn't applied")
+	}
+}
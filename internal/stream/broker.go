@@ -0,0 +1,121 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Subscription receives the messages its match function accepts. C is closed
+// when the subscription ends, either through Unsubscribe or because the
+// subscriber fell too far behind; in the latter case Dropped reports true and
+// the subscriber should resume from the last message it saw.
+type Subscription[T any] struct {
+	C       <-chan T
+	c       chan T
+	match   func(T) bool
+	dropped bool
+}
+
+// Dropped reports whether the broker closed the subscription because its
+// buffer was full.
+func (s *Subscription[T]) Dropped() bool {
+	return s.dropped
+}
+
+// Broker fans messages out to in-process subscribers. Messages reach it
+// through PostgreSQL LISTEN/NOTIFY, so every server instance sees every one.
+type Broker[T any] struct {
+	mu   sync.Mutex
+	subs map[*Subscription[T]]struct{}
+}
+
+func NewBroker[T any]() *Broker[T] {
+	return &Broker[T]{subs: map[*Subscription[T]]struct{}{}}
+}
+
+// Subscribe registers a subscriber with room for buffer undelivered messages.
+func (b *Broker[T]) Subscribe(match func(T) bool, buffer int) *Subscription[T] {
+	c := make(chan T, buffer)
+	s := &Subscription[T]{C: c, c: c, match: match}
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+// Unsubscribe removes the subscription and closes its channel. It is safe to
+// call more than once.
+func (b *Broker[T]) Unsubscribe(s *Subscription[T]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.c)
+	}
+}
+
+// Publish delivers the message to every matching subscriber without blocking.
+// Subscribers whose buffer is full are dropped.
+func (b *Broker[T]) Publish(msg T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		if !s.match(msg) {
+			continue
+		}
+		select {
+		case s.c <- msg:
+		default:
+			s.dropped = true
+			delete(b.subs, s)
+			close(s.c)
+		}
+	}
+}
+
+// JSON decodes NOTIFY payloads that carry the whole message.
+func JSON[T any](_ context.Context, payload string) (T, error) {
+	var msg T
+	err := json.Unmarshal([]byte(payload), &msg)
+	return msg, err
+}
+
+// Listen feeds the broker from NOTIFYs on channel, turned into messages by
+// decode, until ctx is cancelled. The underlying listener reconnects on its
+// own; messages sent while it was disconnected are lost to live subscribers.
+func (b *Broker[T]) Listen(ctx context.Context, dbURL, channel string, decode func(context.Context, string) (T, error)) error {
+	listener := pq.NewListener(dbURL, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Listener on %s: %s", channel, err)
+		}
+	})
+	defer listener.Close()
+	if err := listener.Listen(channel); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-listener.Notify:
+			if n == nil {
+				continue
+			}
+			msg, err := decode(ctx, n.Extra)
+			if err != nil {
+				log.Printf("Error decoding %s payload: %s", channel, err)
+				continue
+			}
+			b.Publish(msg)
+		case <-ticker.C:
+			go listener.Ping()
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package stream
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/visibility"
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// TestChirpEventsCommitInIDOrder runs against the database in
+// CHIRPY_TEST_DB_URL, which must be migrated, and is skipped without one.
+func TestChirpEventsCommitInIDOrder(t *testing.T) {
+	dbURL := os.Getenv("CHIRPY_TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("CHIRPY_TEST_DB_URL not set")
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	q := database.New(db)
+	params := database.CreateChirpEventParams{
+		Type:       EventChirpCreated,
+		ChirpID:    uuid.New(),
+		UserID:     uuid.New(),
+		Body:       "ordering test",
+		Visibility: visibility.Public,
+	}
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM chirp_events WHERE user_id=$1", params.UserID)
+	})
+
+	first, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Rollback()
+	e1, err := q.WithTx(first).CreateChirpEvent(ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second transaction inserts after the first and tries to commit
+	// before it.
+	type result struct {
+		event database.ChirpEvent
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		second, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer second.Rollback()
+		e2, err := q.WithTx(second).CreateChirpEvent(ctx, params)
+		if err == nil {
+			err = second.Commit()
+		}
+		done <- result{e2, err}
+	}()
+	select {
+	case <-done:
+		t.Fatal("second transaction committed before the first")
+	case <-time.After(200 * time.Millisecond):
+	}
+	visible, err := q.ListChirpEventsAfter(ctx, database.ListChirpEventsAfterParams{ID: e1.ID - 1, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(visible) != 0 {
+		t.Fatalf("ListChirpEventsAfter() = %d events before the first commit, want 0", len(visible))
+	}
+
+	if err = first.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	r := <-done
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if r.event.ID <= e1.ID {
+		t.Errorf("second event ID = %d, want greater than %d", r.event.ID, e1.ID)
+	}
+}
@@ -0,0 +1,98 @@
+package stream
+
+import (
+	"chirpy/internal/visibility"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventChirpCreated = "chirp.created"
+	EventChirpDeleted = "chirp.deleted"
+)
+
+// PostgreSQL NOTIFY channels events are published on.
+const (
+	ChirpChannel        = "chirp_events"
+	NotificationChannel = "notification_events"
+)
+
+// Event is a change to a chirp, as delivered to stream subscribers.
+type Event struct {
+	ID         int64     `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	Type       string    `json:"type"`
+	ChirpID    uuid.UUID `json:"chirp_id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Body       string    `json:"body"`
+	Visibility string    `json:"visibility"`
+}
+
+// Notification is a newly created notification, delivered only to the user
+// it is addressed to.
+type Notification struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Type      string     `json:"type"`
+	ActorID   *uuid.UUID `json:"actor_id"`
+	ChirpID   *uuid.UUID `json:"chirp_id"`
+	Message   string     `json:"message"`
+}
+
+// Filter selects the events a subscriber is interested in. Zero fields match
+// everything, except that events are always limited to the chirps ViewerID
+// may see: non-public chirps are only streamed to their author and, for
+// followers-only chirps, to viewers following the author. Unlisted chirps
+// are also streamed to subscribers of that single chirp.
+type Filter struct {
+	AuthorID      uuid.UUID
+	ChirpID       uuid.UUID
+	Hashtag       string
+	HiddenAuthors []uuid.UUID
+	ViewerID      uuid.UUID
+	Following     []uuid.UUID
+}
+
+// Match reports whether the event passes the filter.
+func (f Filter) Match(e Event) bool {
+	if f.AuthorID != uuid.Nil && e.UserID != f.AuthorID {
+		return false
+	}
+	if f.ChirpID != uuid.Nil && e.ChirpID != f.ChirpID {
+		return false
+	}
+	if slices.Contains(f.HiddenAuthors, e.UserID) {
+		return false
+	}
+	// Events published before visibility existed are public.
+	v := e.Visibility
+	if v == "" {
+		v = visibility.Public
+	}
+	if !visibility.CanView(v, e.UserID, f.ViewerID, slices.Contains(f.Following, e.UserID), f.ChirpID != uuid.Nil) {
+		return false
+	}
+	if f.Hashtag != "" && !slices.Contains(Hashtags(e.Body), strings.ToLower(strings.TrimPrefix(f.Hashtag, "#"))) {
+		return false
+	}
+	return true
+}
+
+var hashtagPattern = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_]+)`)
+
+// Hashtags returns the lowercased hashtags in a chirp body, without the '#'.
+func Hashtags(body string) []string {
+	tags := []string{}
+	for _, m := range hashtagPattern.FindAllStringSubmatch(body, -1) {
+		tag := strings.ToLower(m[1])
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
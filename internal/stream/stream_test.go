@@ -0,0 +1,204 @@
+package stream
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestHashtags(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "No hashtags",
+			body: "just chirping",
+			want: []string{},
+		},
+		{
+			name: "Mixed case and duplicates",
+			body: "#Go is fun, #go #Chirpy",
+			want: []string{"go", "chirpy"},
+		},
+		{
+			name: "Unicode hashtag",
+			body: "dzień dobry #Kraków",
+			want: []string{"kraków"},
+		},
+		{
+			name: "Anchors in URLs are not hashtags",
+			body: "see https://example.com/#section",
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Hashtags(tt.body); !slices.Equal(got, tt.want) {
+				t.Errorf("Hashtags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	author := uuid.New()
+	other := uuid.New()
+	event := Event{ID: 1, Type: EventChirpCreated, UserID: author, Body: "hello #Chirpy"}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{
+			name:   "Empty filter",
+			filter: Filter{},
+			want:   true,
+		},
+		{
+			name:   "Matching author",
+			filter: Filter{AuthorID: author},
+			want:   true,
+		},
+		{
+			name:   "Other author",
+			filter: Filter{AuthorID: other},
+			want:   false,
+		},
+		{
+			name:   "Other chirp",
+			filter: Filter{ChirpID: uuid.New()},
+			want:   false,
+		},
+		{
+			name:   "Matching hashtag with prefix",
+			filter: Filter{Hashtag: "#chirpy"},
+			want:   true,
+		},
+		{
+			name:   "Other hashtag",
+			filter: Filter{Hashtag: "golang"},
+			want:   false,
+		},
+		{
+			name:   "Hidden author",
+			filter: Filter{HiddenAuthors: []uuid.UUID{author}},
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(event); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterMatchVisibility(t *testing.T) {
+	author := uuid.New()
+	viewer := uuid.New()
+	chirpID := uuid.New()
+
+	tests := []struct {
+		name       string
+		visibility string
+		filter     Filter
+		want       bool
+	}{
+		{
+			name:       "Public to anyone",
+			visibility: "public",
+			filter:     Filter{},
+			want:       true,
+		},
+		{
+			name:       "Unlisted on the timeline",
+			visibility: "unlisted",
+			filter:     Filter{ViewerID: viewer},
+			want:       false,
+		},
+		{
+			name:       "Unlisted to a subscriber of the chirp",
+			visibility: "unlisted",
+			filter:     Filter{ChirpID: chirpID, ViewerID: viewer},
+			want:       true,
+		},
+		{
+			name:       "Followers-only to a follower",
+			visibility: "followers",
+			filter:     Filter{ViewerID: viewer, Following: []uuid.UUID{author}},
+			want:       true,
+		},
+		{
+			name:       "Followers-only to someone else",
+			visibility: "followers",
+			filter:     Filter{ViewerID: viewer},
+			want:       false,
+		},
+		{
+			name:       "Followers-only to its author",
+			visibility: "followers",
+			filter:     Filter{ViewerID: author},
+			want:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := Event{ID: 1, ChirpID: chirpID, UserID: author, Visibility: tt.visibility}
+			if got := tt.filter.Match(event); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBrokerFanOut(t *testing.T) {
+	b := NewBroker[Event]()
+	author := uuid.New()
+	all := b.Subscribe(Filter{}.Match, 4)
+	byAuthor := b.Subscribe(Filter{AuthorID: author}.Match, 4)
+	defer b.Unsubscribe(all)
+	defer b.Unsubscribe(byAuthor)
+
+	b.Publish(Event{ID: 1, UserID: uuid.New()})
+	b.Publish(Event{ID: 2, UserID: author})
+
+	if e := <-all.C; e.ID != 1 {
+		t.Errorf("first event = %v, want 1", e.ID)
+	}
+	if e := <-all.C; e.ID != 2 {
+		t.Errorf("second event = %v, want 2", e.ID)
+	}
+	if e := <-byAuthor.C; e.ID != 2 {
+		t.Errorf("filtered event = %v, want 2", e.ID)
+	}
+	if len(byAuthor.C) != 0 {
+		t.Errorf("filtered subscription has %d extra events", len(byAuthor.C))
+	}
+}
+
+func TestBrokerDropsSlowSubscribers(t *testing.T) {
+	b := NewBroker[Event]()
+	slow := b.Subscribe(Filter{}.Match, 1)
+
+	b.Publish(Event{ID: 1})
+	b.Publish(Event{ID: 2})
+
+	if e, ok := <-slow.C; !ok || e.ID != 1 {
+		t.Fatalf("buffered event = %v, %v, want 1, true", e.ID, ok)
+	}
+	if _, ok := <-slow.C; ok {
+		t.Fatal("subscription still open after overflowing")
+	}
+	if !slow.Dropped() {
+		t.Error("Dropped() = false, want true")
+	}
+	b.Unsubscribe(slow)
+}
@@ -0,0 +1,110 @@
+package subscriptions
+
+import "time"
+
+// Subscription statuses. Past due subscriptions keep their entitlement until
+// the end of the paid period; canceled and expired ones have none left.
+const (
+	StatusActive   = "active"
+	StatusPastDue  = "past_due"
+	StatusCanceled = "canceled"
+	StatusExpired  = "expired"
+)
+
+// Polka events that change a subscription.
+const (
+	EventUpgraded      = "user.upgraded"
+	EventDowngraded    = "user.downgraded"
+	EventRenewed       = "subscription.renewed"
+	EventPaymentFailed = "payment.failed"
+)
+
+const (
+	DefaultPlan = "chirpy_red"
+	// Period is the length of a billing period when an event doesn't say.
+	Period = 30 * 24 * time.Hour
+)
+
+type Subscription struct {
+	Plan        string
+	Status      string
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+}
+
+// Event is a subscription change. Plan and the period bounds are optional.
+type Event struct {
+	Type        string
+	Plan        string
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+}
+
+// Apply returns the subscription after ev happened at now, given the current
+// one (nil if the user never subscribed). It reports false when ev doesn't
+// apply: an unknown event, or one about a subscription the user doesn't have.
+func Apply(sub *Subscription, ev Event, now time.Time) (Subscription, bool) {
+	switch ev.Type {
+	case EventUpgraded:
+		next := Subscription{Plan: ev.Plan, Status: StatusActive, PeriodStart: now}
+		if next.Plan == "" {
+			next.Plan = DefaultPlan
+		}
+		return withPeriod(next, ev), true
+	case EventRenewed:
+		if sub == nil {
+			return Apply(nil, Event{Type: EventUpgraded, Plan: ev.Plan, PeriodStart: ev.PeriodStart, PeriodEnd: ev.PeriodEnd}, now)
+		}
+		next := *sub
+		next.Status = StatusActive
+		if ev.Plan != "" {
+			next.Plan = ev.Plan
+		}
+		// An early renewal extends the current period instead of cutting it
+		// short.
+		next.PeriodStart = now
+		if sub.Status != StatusExpired && sub.Status != StatusCanceled && sub.PeriodEnd.After(now) {
+			next.PeriodStart = sub.PeriodEnd
+		}
+		return withPeriod(next, ev), true
+	case EventPaymentFailed:
+		if sub == nil || !Entitled(*sub, now) {
+			return Subscription{}, false
+		}
+		next := *sub
+		next.Status = StatusPastDue
+		return next, true
+	case EventDowngraded:
+		if sub == nil || sub.Status == StatusCanceled || sub.Status == StatusExpired {
+			return Subscription{}, false
+		}
+		next := *sub
+		next.Status = StatusCanceled
+		if next.PeriodEnd.After(now) {
+			next.PeriodEnd = now
+		}
+		return next, true
+	}
+	return Subscription{}, false
+}
+
+// withPeriod sets the period given by ev, or one default-length period from
+// sub.PeriodStart.
+func withPeriod(sub Subscription, ev Event) Subscription {
+	if !ev.PeriodStart.IsZero() {
+		sub.PeriodStart = ev.PeriodStart
+	}
+	sub.PeriodEnd = sub.PeriodStart.Add(Period)
+	if !ev.PeriodEnd.IsZero() {
+		sub.PeriodEnd = ev.PeriodEnd
+	}
+	return sub
+}
+
+// Entitled reports whether sub grants its plan's features at now.
+func Entitled(sub Subscription, now time.Time) bool {
+	if sub.Status != StatusActive && sub.Status != StatusPastDue {
+		return false
+	}
+	return now.Before(sub.PeriodEnd)
+}
@@ -0,0 +1,142 @@
+package subscriptions
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApply(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	active := &Subscription{Plan: DefaultPlan, Status: StatusActive, PeriodStart: now.Add(-10 * 24 * time.Hour), PeriodEnd: now.Add(20 * 24 * time.Hour)}
+	lapsed := &Subscription{Plan: DefaultPlan, Status: StatusExpired, PeriodStart: now.Add(-40 * 24 * time.Hour), PeriodEnd: now.Add(-10 * 24 * time.Hour)}
+	tests := []struct {
+		name      string
+		sub       *Subscription
+		ev        Event
+		want      Subscription
+		wantApply bool
+	}{
+		{
+			name:      "Upgrade starts a default period",
+			sub:       nil,
+			ev:        Event{Type: EventUpgraded},
+			want:      Subscription{Plan: DefaultPlan, Status: StatusActive, PeriodStart: now, PeriodEnd: now.Add(Period)},
+			wantApply: true,
+		},
+		{
+			name:      "Upgrade uses the given plan and period",
+			sub:       nil,
+			ev:        Event{Type: EventUpgraded, Plan: "yearly", PeriodStart: now.Add(-time.Hour), PeriodEnd: now.Add(365 * 24 * time.Hour)},
+			want:      Subscription{Plan: "yearly", Status: StatusActive, PeriodStart: now.Add(-time.Hour), PeriodEnd: now.Add(365 * 24 * time.Hour)},
+			wantApply: true,
+		},
+		{
+			name:      "Early renewal extends the period",
+			sub:       active,
+			ev:        Event{Type: EventRenewed},
+			want:      Subscription{Plan: DefaultPlan, Status: StatusActive, PeriodStart: active.PeriodEnd, PeriodEnd: active.PeriodEnd.Add(Period)},
+			wantApply: true,
+		},
+		{
+			name:      "Renewal after expiry starts now",
+			sub:       lapsed,
+			ev:        Event{Type: EventRenewed},
+			want:      Subscription{Plan: DefaultPlan, Status: StatusActive, PeriodStart: now, PeriodEnd: now.Add(Period)},
+			wantApply: true,
+		},
+		{
+			name:      "Renewal without a subscription subscribes",
+			sub:       nil,
+			ev:        Event{Type: EventRenewed},
+			want:      Subscription{Plan: DefaultPlan, Status: StatusActive, PeriodStart: now, PeriodEnd: now.Add(Period)},
+			wantApply: true,
+		},
+		{
+			name:      "Failed payment keeps the period",
+			sub:       active,
+			ev:        Event{Type: EventPaymentFailed},
+			want:      Subscription{Plan: DefaultPlan, Status: StatusPastDue, PeriodStart: active.PeriodStart, PeriodEnd: active.PeriodEnd},
+			wantApply: true,
+		},
+		{
+			name:      "Failed payment on a lapsed subscription",
+			sub:       lapsed,
+			ev:        Event{Type: EventPaymentFailed},
+			wantApply: false,
+		},
+		{
+			name:      "Downgrade ends the period now",
+			sub:       active,
+			ev:        Event{Type: EventDowngraded},
+			want:      Subscription{Plan: DefaultPlan, Status: StatusCanceled, PeriodStart: active.PeriodStart, PeriodEnd: now},
+			wantApply: true,
+		},
+		{
+			name:      "Downgrade without a subscription",
+			sub:       nil,
+			ev:        Event{Type: EventDowngraded},
+			wantApply: false,
+		},
+		{
+			name:      "Unknown event",
+			sub:       active,
+			ev:        Event{Type: "user.deleted"},
+			wantApply: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, applied := Apply(tt.sub, tt.ev, now)
+			if applied != tt.wantApply {
+				t.Fatalf("Apply() applied = %v, want %v", applied, tt.wantApply)
+			}
+			if got != tt.want {
+				t.Errorf("Apply() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntitled(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		sub  Subscription
+		want bool
+	}{
+		{
+			name: "Active in period",
+			sub:  Subscription{Status: StatusActive, PeriodEnd: now.Add(time.Hour)},
+			want: true,
+		},
+		{
+			name: "Past due in period",
+			sub:  Subscription{Status: StatusPastDue, PeriodEnd: now.Add(time.Hour)},
+			want: true,
+		},
+		{
+			name: "Active after period end",
+			sub:  Subscription{Status: StatusActive, PeriodEnd: now},
+			want: false,
+		},
+		{
+			name: "Canceled",
+			sub:  Subscription{Status: StatusCanceled, PeriodEnd: now.Add(time.Hour)},
+			want: false,
+		},
+		{
+			name: "Expired",
+			sub:  Subscription{Status: StatusExpired, PeriodEnd: now.Add(time.Hour)},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Entitled(tt.sub, now); got != tt.want {
+				t.Errorf("Entitled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package visibility
+
+import "github.com/google/uuid"
+
+const (
+	// Public chirps are shown to everyone, everywhere.
+	Public = "public"
+	// Followers chirps are only shown to the author and their followers.
+	Followers = "followers"
+	// Unlisted chirps are shown to anyone with a direct link, but left out
+	// of listings and streams.
+	Unlisted = "unlisted"
+)
+
+// Valid reports whether v is a known visibility. The empty string is not
+// valid; callers default it to Public.
+func Valid(v string) bool {
+	return v == Public || v == Followers || v == Unlisted
+}
+
+// CanView reports whether viewerID may see a chirp by authorID with
+// visibility v. viewerID is uuid.Nil for anonymous viewers. direct is true
+// when the chirp is asked for on its own, by ID, rather than as part of a
+// listing or stream.
+func CanView(v string, authorID, viewerID uuid.UUID, isFollower, direct bool) bool {
+	if viewerID != uuid.Nil && viewerID == authorID {
+		return true
+	}
+	switch v {
+	case Public:
+		return true
+	case Unlisted:
+		return direct
+	case Followers:
+		return viewerID != uuid.Nil && isFollower
+	}
+	return false
+}
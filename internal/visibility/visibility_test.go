@@ -0,0 +1,90 @@
+package visibility
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCanView(t *testing.T) {
+	author := uuid.New()
+	viewer := uuid.New()
+	tests := []struct {
+		name       string
+		visibility string
+		viewerID   uuid.UUID
+		isFollower bool
+		direct     bool
+		want       bool
+	}{
+		{
+			name:       "Public in a listing",
+			visibility: Public,
+			viewerID:   uuid.Nil,
+			want:       true,
+		},
+		{
+			name:       "Unlisted in a listing",
+			visibility: Unlisted,
+			viewerID:   viewer,
+			want:       false,
+		},
+		{
+			name:       "Unlisted by direct link",
+			visibility: Unlisted,
+			viewerID:   uuid.Nil,
+			direct:     true,
+			want:       true,
+		},
+		{
+			name:       "Unlisted to its author",
+			visibility: Unlisted,
+			viewerID:   author,
+			want:       true,
+		},
+		{
+			name:       "Followers to a follower",
+			visibility: Followers,
+			viewerID:   viewer,
+			isFollower: true,
+			want:       true,
+		},
+		{
+			name:       "Followers to someone else",
+			visibility: Followers,
+			viewerID:   viewer,
+			direct:     true,
+			want:       false,
+		},
+		{
+			name:       "Followers to an anonymous viewer",
+			visibility: Followers,
+			viewerID:   uuid.Nil,
+			isFollower: true,
+			direct:     true,
+			want:       false,
+		},
+		{
+			name:       "Followers to its author",
+			visibility: Followers,
+			viewerID:   author,
+			want:       true,
+		},
+		{
+			name:       "Unknown visibility",
+			visibility: "secret",
+			viewerID:   viewer,
+			direct:     true,
+			want:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CanView(tt.visibility, author, tt.viewerID, tt.isFollower, tt.direct)
+			if got != tt.want {
+				t.Errorf("CanView() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Events endpoints can subscribe to.
+const (
+	EventChirpCreated = "chirp.created"
+	EventChirpDeleted = "chirp.deleted"
+	EventUserFollowed = "user.followed"
+)
+
+var Events = []string{EventChirpCreated, EventChirpDeleted, EventUserFollowed}
+
+// Headers sent with every delivery. The signature is made with
+// auth.SignWebhook over the timestamp and raw body.
+const (
+	SignatureHeader = "X-Chirpy-Signature"
+	TimestampHeader = "X-Chirpy-Timestamp"
+	EventHeader     = "X-Chirpy-Event"
+	DeliveryHeader  = "X-Chirpy-Delivery"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it's failed.
+	MaxAttempts = 8
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+)
+
+// Envelope is the body of a delivery. ID is the same for every endpoint and
+// redelivery of an event, so receivers can deduplicate.
+type Envelope struct {
+	ID        uuid.UUID       `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+func ValidEvent(event string) bool {
+	return slices.Contains(Events, event)
+}
+
+// ErrForbiddenAddress is returned for endpoints on loopback, private,
+// link-local or otherwise internal addresses, which deliveries must not
+// reach.
+var ErrForbiddenAddress = errors.New("webhook URL must not point to an internal address")
+
+// ValidateURL checks that an endpoint URL is an absolute http(s) URL and
+// isn't obviously internal. Hostnames are only resolved when delivering, by
+// the client from NewClient, since what they resolve to can change.
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("webhook URL must be an absolute http or https URL")
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrForbiddenAddress
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !AllowedAddr(addr) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// AllowedAddr reports whether deliveries may connect to addr: anything but
+// loopback, private, link-local, multicast and unspecified addresses.
+func AllowedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() && !addr.IsLoopback() && !addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() && !addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() && !addr.IsMulticast() && !addr.IsUnspecified()
+}
+
+// dialControl refuses connections to addresses that aren't allowed. It runs
+// after the hostname has been resolved, for every address tried, so DNS
+// can't be used to point an endpoint at an internal address.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !AllowedAddr(addrPort.Addr()) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// NewClient returns an HTTP client for deliveries. It only connects to
+// allowed addresses, ignores proxy settings, and doesn't follow redirects,
+// which could otherwise lead from a public URL to an internal one.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: dialControl}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Backoff is how long to wait before retrying a delivery that has failed
+// attempts times: 30s doubling each time, up to 6h.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+	backoff := baseBackoff
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
+
+// NewSecret generates a signing secret for an endpoint.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,163 @@
+package webhooks
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		want     time.Duration
+	}{
+		{
+			name:     "No attempts",
+			attempts: 0,
+			want:     0,
+		},
+		{
+			name:     "First failure",
+			attempts: 1,
+			want:     30 * time.Second,
+		},
+		{
+			name:     "Third failure",
+			attempts: 3,
+			want:     2 * time.Minute,
+		},
+		{
+			name:     "Capped",
+			attempts: 20,
+			want:     6 * time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Backoff(tt.attempts); got != tt.want {
+				t.Errorf("Backoff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{
+			name:    "HTTPS URL",
+			url:     "https://example.com/hooks/chirpy",
+			wantErr: false,
+		},
+		{
+			name:    "HTTP URL",
+			url:     "http://hooks.example.com:9000/hook",
+			wantErr: false,
+		},
+		{
+			name:    "Localhost",
+			url:     "http://localhost:9000/hook",
+			wantErr: true,
+		},
+		{
+			name:    "Loopback address",
+			url:     "http://127.0.0.1:9000/hook",
+			wantErr: true,
+		},
+		{
+			name:    "Cloud metadata address",
+			url:     "http://169.254.169.254/latest/meta-data/",
+			wantErr: true,
+		},
+		{
+			name:    "Private IPv6 address",
+			url:     "http://[fd00::1]/hook",
+			wantErr: true,
+		},
+		{
+			name:    "Relative URL",
+			url:     "/hooks/chirpy",
+			wantErr: true,
+		},
+		{
+			name:    "Other scheme",
+			url:     "ftp://example.com/hook",
+			wantErr: true,
+		},
+		{
+			name:    "Empty",
+			url:     "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAllowedAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "93.184.215.14", want: true},
+		{addr: "2606:2800:21f:cb07:6820:80da:af6b:8b2c", want: true},
+		{addr: "127.0.0.1", want: false},
+		{addr: "::1", want: false},
+		{addr: "10.1.2.3", want: false},
+		{addr: "172.16.0.1", want: false},
+		{addr: "192.168.1.1", want: false},
+		{addr: "169.254.169.254", want: false},
+		{addr: "fe80::1", want: false},
+		{addr: "0.0.0.0", want: false},
+		{addr: "::ffff:127.0.0.1", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := AllowedAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("AllowedAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t.Error("client reached a loopback server")
+	}))
+	defer server.Close()
+	_, err := NewClient(time.Second).Post(server.URL, "application/json", nil)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("Post() error = %v, want ErrForbiddenAddress", err)
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	a, err := NewSecret()
+	if err != nil {
+		t.Fatalf("NewSecret() error = %v", err)
+	}
+	b, _ := NewSecret()
+	if !strings.HasPrefix(a, "whsec_") || len(a) != len("whsec_")+64 {
+		t.Errorf("NewSecret() = %q, want whsec_ and 64 hex digits", a)
+	}
+	if a == b {
+		t.Error("NewSecret() returned the same secret twice")
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/jobs"
+	"chirpy/internal/pagination"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	jobPollInterval = 2 * time.Second
+	jobLease        = 5 * time.Minute
+	// jobTimeout is kept well inside the lease so a job is given up on
+	// before another worker can reclaim it.
+	jobTimeout         = 4 * time.Minute
+	defaultJobAttempts = 10
+)
+
+// Job kinds.
+const (
+	jobExpireSubscriptions  = "subscriptions.expire"
+	jobCollectRefreshTokens = "refresh_tokens.collect"
+	jobCollectHistory       = "history.collect"
+	jobProcessMedia         = "media.process"
+)
+
+type Job struct {
+	ID          uuid.UUID       `json:"id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	UniqueKey   *string         `json:"unique_key"`
+	Attempts    int32           `json:"attempts"`
+	MaxAttempts int32           `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	LastError   *string         `json:"last_error"`
+	FinishedAt  *time.Time      `json:"finished_at"`
+}
+
+type JobPage struct {
+	Jobs       []Job  `json:"jobs"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+func jobFromDB(j database.Job) Job {
+	job := Job{
+		ID:          j.ID,
+		CreatedAt:   j.CreatedAt,
+		UpdatedAt:   j.UpdatedAt,
+		Kind:        j.Kind,
+		Payload:     j.Payload,
+		Status:      j.Status,
+		Attempts:    j.Attempts,
+		MaxAttempts: j.MaxAttempts,
+		RunAt:       j.RunAt,
+		FinishedAt:  nullTimePtr(j.FinishedAt),
+	}
+	if j.UniqueKey.Valid {
+		job.UniqueKey = &j.UniqueKey.String
+	}
+	if j.LastError.Valid {
+		job.LastError = &j.LastError.String
+	}
+	return job
+}
+
+// jobOptions tune how a job is enqueued. A job with a UniqueKey isn't
+// enqueued while another of the same kind and key is waiting or running.
+type jobOptions struct {
+	UniqueKey   string
+	RunAt       time.Time
+	MaxAttempts int
+}
+
+// enqueueJob queues a job and reports whether it was, rather than skipped
+// for its unique key. Pass queries bound to a transaction to enqueue the job
+// only if the transaction commits.
+func enqueueJob(ctx context.Context, q *database.Queries, kind string, payload any, opts jobOptions) (bool, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+	params := database.EnqueueJobParams{
+		Kind:        kind,
+		Payload:     raw,
+		MaxAttempts: int32(opts.MaxAttempts),
+		RunAt:       opts.RunAt.UTC(),
+	}
+	if params.MaxAttempts <= 0 {
+		params.MaxAttempts = defaultJobAttempts
+	}
+	if opts.RunAt.IsZero() {
+		params.RunAt = time.Now().UTC()
+	}
+	if opts.UniqueKey != "" {
+		params.UniqueKey = sql.NullString{String: opts.UniqueKey, Valid: true}
+	}
+	_, err = q.EnqueueJob(ctx, params)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// registerJobs sets up the handlers for every job kind.
+func (cfg *apiConfig) registerJobs() {
+	cfg.jobs = jobs.NewRegistry()
+	jobs.Register(cfg.jobs, jobExpireSubscriptions, func(ctx context.Context, _ struct{}) error {
+		return cfg.expireSubscriptions(ctx)
+	})
+	jobs.Register(cfg.jobs, jobCollectRefreshTokens, cfg.collectRefreshTokensJob)
+	jobs.Register(cfg.jobs, jobCollectHistory, cfg.collectHistoryJob)
+	jobs.Register(cfg.jobs, jobProcessMedia, cfg.processMediaJob)
+}
+
+// runPeriodicJob queues a job of the given kind every interval until ctx is
+// done. The kind doubles as the job's unique key, so instances don't pile up
+// copies of it.
+func runPeriodicJob(ctx context.Context, q *database.Queries, kind string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		_, err := enqueueJob(ctx, q, kind, struct{}{}, jobOptions{UniqueKey: kind, MaxAttempts: 1})
+		if err != nil {
+			log.Printf("Error queueing %s job: %s", kind, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runJobWorkers runs queued jobs with the given number of workers until ctx
+// is done, letting jobs that are running finish. Jobs are claimed with FOR
+// UPDATE SKIP LOCKED under a lease, so every instance can run workers, and a
+// job whose worker died is picked up again once its lease runs out.
+func (cfg *apiConfig) runJobWorkers(ctx context.Context, workers int) {
+	for range workers {
+		cfg.startWorker(func() {
+			ticker := time.NewTicker(jobPollInterval)
+			defer ticker.Stop()
+			for {
+				for ctx.Err() == nil {
+					cfg.heartbeats.Beat("jobs", jobPollInterval, time.Now())
+					ran, err := cfg.runNextJob(context.WithoutCancel(ctx))
+					if err != nil {
+						log.Printf("Error running job: %s", err)
+						break
+					}
+					if !ran {
+						break
+					}
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		})
+	}
+}
+
+// runNextJob runs the next due job and reports whether there was one.
+// Failed jobs are retried with exponential backoff until they run out of
+// attempts or fail permanently, at which point they're dead.
+func (cfg *apiConfig) runNextJob(ctx context.Context) (bool, error) {
+	now := time.Now().UTC()
+	job, err := cfg.dbQueries.ClaimJob(ctx, database.ClaimJobParams{Now: now, LockedUntil: now.Add(jobLease)})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	jobCtx, cancel := context.WithTimeout(ctx, jobTimeout)
+	err = cfg.jobs.Run(jobCtx, job.Kind, job.Payload)
+	cancel()
+	var updated int64
+	switch {
+	case err == nil:
+		updated, err = cfg.dbQueries.CompleteJob(ctx, database.CompleteJobParams{ID: job.ID, Attempts: job.Attempts})
+	case jobs.IsPermanent(err) || job.Attempts >= job.MaxAttempts:
+		log.Printf("Job %s (%s) failed: %s", job.ID, job.Kind, err)
+		updated, err = cfg.dbQueries.KillJob(ctx, database.KillJobParams{
+			ID:        job.ID,
+			Attempts:  job.Attempts,
+			LastError: sql.NullString{String: err.Error(), Valid: true},
+		})
+	default:
+		log.Printf("Job %s (%s) failed: %s", job.ID, job.Kind, err)
+		updated, err = cfg.dbQueries.RetryJobLater(ctx, database.RetryJobLaterParams{
+			ID:        job.ID,
+			Attempts:  job.Attempts,
+			LastError: sql.NullString{String: err.Error(), Valid: true},
+			RunAt:     time.Now().UTC().Add(jobs.Backoff(int(job.Attempts))),
+		})
+	}
+	if err == nil && updated == 0 {
+		log.Printf("Job %s (%s) lost its lease; leaving its state to the worker that reclaimed it", job.ID, job.Kind)
+	}
+	return true, err
+}
+
+func (cfg *apiConfig) handlerAdminJobsList(w http.ResponseWriter, req *http.Request) {
+	if !cfg.requireAdmin(w, req) {
+		return
+	}
+	limit, err := pagination.Limit(req.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	params := database.ListJobsParams{MaxResults: int32(limit)}
+	if status := req.URL.Query().Get("status"); status != "" {
+		params.Status = sql.NullString{String: status, Valid: true}
+	}
+	if kind := req.URL.Query().Get("kind"); kind != "" {
+		params.Kind = sql.NullString{String: kind, Valid: true}
+	}
+	if c := req.URL.Query().Get("cursor"); c != "" {
+		cursor, err := pagination.Decode(c)
+		if err != nil {
+			respondWithError(w, 400, err.Error())
+			return
+		}
+		params.BeforeCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	rows, err := cfg.dbQueries.ListJobs(req.Context(), params)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	page := JobPage{Jobs: make([]Job, len(rows))}
+	for i, row := range rows {
+		page.Jobs[i] = jobFromDB(row)
+	}
+	if len(rows) == limit {
+		last := rows[len(rows)-1]
+		page.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	respondWithJSON(w, 200, page)
+}
+
+func (cfg *apiConfig) handlerAdminJobGet(w http.ResponseWriter, req *http.Request) {
+	if !cfg.requireAdmin(w, req) {
+		return
+	}
+	jobID, err := uuid.Parse(req.PathValue("jobID"))
+	if err != nil {
+		respondWithError(w, 404, "Job not found")
+		return
+	}
+	job, err := cfg.dbQueries.GetJob(req.Context(), jobID)
+	if err != nil {
+		respondWithError(w, 404, "Job not found")
+		return
+	}
+	respondWithJSON(w, 200, jobFromDB(job))
+}
+
+// handlerAdminJobRetry requeues a dead job with a fresh set of attempts.
+func (cfg *apiConfig) handlerAdminJobRetry(w http.ResponseWriter, req *http.Request) {
+	if !cfg.requireAdmin(w, req) {
+		return
+	}
+	jobID, err := uuid.Parse(req.PathValue("jobID"))
+	if err != nil {
+		respondWithError(w, 404, "Job not found")
+		return
+	}
+	job, err := cfg.dbQueries.GetJob(req.Context(), jobID)
+	if err != nil {
+		respondWithError(w, 404, "Job not found")
+		return
+	}
+	if job.Status != jobs.StatusDead {
+		respondWithError(w, 409, "Only dead jobs can be retried")
+		return
+	}
+	job, err = cfg.dbQueries.RequeueDeadJob(req.Context(), jobID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 409, "Only dead jobs can be retried")
+		return
+	}
+	if err != nil {
+		// Another job with the same unique key is already queued.
+		respondWithError(w, 409, "A job with the same unique key is already queued")
+		return
+	}
+	respondWithJSON(w, 200, jobFromDB(job))
+}
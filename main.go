@@ -2,16 +2,28 @@ package main
 
 import (
 	"chirpy/internal/auth"
+	"chirpy/internal/blobstore"
+	"chirpy/internal/config"
 	"chirpy/internal/database"
+	"chirpy/internal/entitlements"
+	"chirpy/internal/health"
+	"chirpy/internal/jobs"
+	"chirpy/internal/metrics"
+	"chirpy/internal/outbox"
+	"chirpy/internal/ratelimit"
+	"chirpy/internal/stream"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"slices"
+	"os/signal"
 	"strings"
-	"sync/atomic"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
@@ -21,123 +33,158 @@ import (
 
 func main() {
 	godotenv.Load()
-	dbURL := os.Getenv("DB_URL")
+	conf, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%s", err)
+	}
+	// "chirpy config" only prints the configuration, so it works without a
+	// database.
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		fmt.Print(conf.Redacted())
+		return
+	}
+	log.Printf("Effective configuration:\n%s", conf.Redacted())
+	dbURL := conf.DBURL
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
-		log.Print(err)
+		log.Fatal(err)
 	}
-	serveMux := http.NewServeMux()
-	server := http.Server{Handler: serveMux}
-	server.Addr = ":8080"
+	pingCtx, cancelPing := context.WithTimeout(context.Background(), dbPingTimeout)
+	err = db.PingContext(pingCtx)
+	cancelPing()
+	if err != nil {
+		log.Fatalf("Error connecting to the database: %s", err)
+	}
+	// workCtx stops the background workers at shutdown.
+	workCtx, stopWorkers := context.WithCancel(context.Background())
+	chirpEvents := stream.NewBroker[stream.Event]()
+	go func() {
+		if err := chirpEvents.Listen(workCtx, dbURL, stream.ChirpChannel, chirpEventByID(database.New(db))); err != nil {
+			log.Printf("Error listening for chirp events: %s", err)
+		}
+	}()
+	notificationEvents := stream.NewBroker[stream.Notification]()
+	go func() {
+		if err := notificationEvents.Listen(workCtx, dbURL, stream.NotificationChannel, stream.JSON[stream.Notification]); err != nil {
+			log.Printf("Error listening for notification events: %s", err)
+		}
+	}()
 	apiCfg := &apiConfig{}
-	apiCfg.fileserverHits.Store(0)
+	apiCfg.closing = make(chan struct{})
+	apiCfg.metrics = metrics.New(db)
+	serveMux := http.NewServeMux()
+	server := newServer(apiCfg.metrics.Middleware(limitBody(serveMux, int64(conf.MaxBodyBytes))), conf)
+	blobs, err := newBlobStore(conf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	apiCfg.db = db
+	apiCfg.blobs = blobs
+	apiCfg.chirpEvents = chirpEvents
+	apiCfg.notificationEvents = notificationEvents
 	apiCfg.dbQueries = database.New(db)
-	apiCfg.secret = os.Getenv("JWT_SECRET")
-	apiCfg.polkaKey = os.Getenv("POLKA_KEY")
+	apiCfg.platform = conf.Platform
+	apiCfg.secret = conf.JWTSecret
+	apiCfg.polkaKey = conf.PolkaKey
+	apiCfg.adminKey = conf.AdminAPIKey
+	apiCfg.polkaSecrets = conf.PolkaWebhookSecrets
+	apiCfg.polkaTolerance = time.Duration(conf.PolkaWebhookToleranceSeconds) * time.Second
+	apiCfg.entitlements = entitlements.Default()
+	if conf.EntitlementsFile != "" {
+		if apiCfg.entitlements, err = entitlements.LoadFile(conf.EntitlementsFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	apiCfg.limiter = ratelimit.New()
+	apiCfg.heartbeats = health.NewHeartbeats()
+	if apiCfg.schemaVersion, err = expectedSchemaVersion(); err != nil {
+		log.Fatal(err)
+	}
+	if apiCfg.outboxSinks, err = outboxSinks(conf); err != nil {
+		log.Fatal(err)
+	}
+	apiCfg.tokenRetention = time.Duration(conf.RefreshTokenRetentionDays) * 24 * time.Hour
+	apiCfg.tokenGCBatch = conf.RefreshTokenGCBatch
+	apiCfg.historyRetention = time.Duration(conf.HistoryRetentionDays) * 24 * time.Hour
+	apiCfg.historyGCBatch = conf.HistoryGCBatch
+	if len(os.Args) > 1 {
+		os.Exit(apiCfg.runCommand(context.Background(), os.Args[1:]))
+	}
+	apiCfg.startWorker(func() { apiCfg.runScheduler(workCtx) })
+	apiCfg.registerJobs()
+	apiCfg.runJobWorkers(workCtx, conf.JobWorkers)
+	apiCfg.startWorker(func() {
+		runPeriodicJob(workCtx, apiCfg.dbQueries, jobExpireSubscriptions, subscriptionExpiryInterval)
+	})
+	apiCfg.startWorker(func() {
+		runPeriodicJob(workCtx, apiCfg.dbQueries, jobCollectRefreshTokens, tokenGCInterval)
+	})
+	apiCfg.startWorker(func() {
+		runPeriodicJob(workCtx, apiCfg.dbQueries, jobCollectHistory, historyGCInterval)
+	})
+	apiCfg.runWebhookDeliveries(workCtx, conf.WebhookWorkers)
+	apiCfg.startWorker(func() { apiCfg.runOutboxRelay(workCtx) })
 	serveMux.Handle("/app/", http.StripPrefix("/app", apiCfg.middlewareMetricsInc(http.FileServer(http.Dir("app")))))
 	serveMux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, req *http.Request) {
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		w.WriteHeader(200)
 		w.Write([]byte("OK"))
 	})
+	serveMux.HandleFunc("GET /livez", apiCfg.handlerLivez)
+	serveMux.HandleFunc("GET /readyz", apiCfg.handlerReadyz)
+	serveMux.Handle("GET /metrics", apiCfg.metrics.Handler())
 	serveMux.HandleFunc("GET /admin/metrics", func(w http.ResponseWriter, req *http.Request) {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		w.WriteHeader(200)
-		_, _ = fmt.Fprintf(w, "<html><body><h1>Welcome, Chirpy Admin</h1><p>Chirpy has been visited %d times!</p></body></html>", apiCfg.fileserverHits.Load())
+		_, _ = fmt.Fprintf(w, "<html><body><h1>Welcome, Chirpy Admin</h1><p>Chirpy has been visited %.0f times!</p><p>Stale refresh tokens removed: %.0f</p></body></html>",
+			apiCfg.metrics.Hits(), apiCfg.metrics.Value("chirpy_refresh_tokens_removed_total"))
 	})
 	serveMux.HandleFunc("POST /admin/reset", func(w http.ResponseWriter, req *http.Request) {
-		if platform := os.Getenv("PLATFORM"); platform != "dev" {
+		if apiCfg.platform != "dev" {
 			w.WriteHeader(403)
 		} else {
-			apiCfg.fileserverHits.Store(0)
 			_ = apiCfg.dbQueries.DeleteAllUsers(req.Context())
 			_ = apiCfg.dbQueries.DeleteAllChirps(req.Context())
+			apiCfg.metrics.ResetHits()
 			w.WriteHeader(200)
 		}
 	})
-	serveMux.HandleFunc("POST /api/chirps", func(w http.ResponseWriter, req *http.Request) {
-		token, err := auth.GetBearerToken(req.Header)
-		if err != nil {
-			respondWithError(w, 400, "Error fetching authorization token")
-			return
-		}
-		userID, err := auth.ValidateJWT(token, apiCfg.secret)
-		if err != nil {
-			respondWithError(w, 401, err.Error())
-			return
-		}
-		newChirp := Chirp{}
-		newChirp.UserID = userID
-		decoder := json.NewDecoder(req.Body)
-		err = decoder.Decode(&newChirp)
-		if err != nil {
-			respondWithError(w, 400, "Error decoding chirp")
-			return
-		}
-		if len(newChirp.Body) > 140 {
-			respondWithError(w, 400, "Chirp is too long")
-			return
-		}
-		newChirp.Body = cleanChirp(newChirp.Body)
-		c, err := apiCfg.dbQueries.CreateChirp(req.Context(), database.CreateChirpParams{Body: newChirp.Body, UserID: userID})
-		if err != nil {
-			respondWithError(w, 500, err.Error())
-			return
-		}
-		newChirp.ID = c.ID
-		newChirp.CreatedAt = c.CreatedAt
-		newChirp.UpdatedAt = c.UpdatedAt
-		respondWithJSON(w, 201, newChirp)
-	})
-	serveMux.HandleFunc("GET /api/chirps", func(w http.ResponseWriter, req *http.Request) {
-		var dbChirps []database.Chirp
-		author := req.URL.Query().Get("author_id")
-		if author == "" {
-			dbChirps, err = apiCfg.dbQueries.ListChirps(req.Context())
-			if err != nil {
-				respondWithError(w, 500, err.Error())
-				return
-			}
-		} else {
-			userID, err := uuid.Parse(author)
-			if err != nil {
-				respondWithError(w, 400, "User not found")
-				return
-			}
-			dbChirps, err = apiCfg.dbQueries.ListChirpsFromAuthor(req.Context(), userID)
-			if err != nil {
-				respondWithError(w, 500, err.Error())
-				return
-			}
-		}
-		chirps := make([]Chirp, len(dbChirps))
-		for i := range dbChirps {
-			chirps[i] = Chirp(dbChirps[i])
-		}
-		sort := req.URL.Query().Get("sort")
-		switch sort {
-		case "", "asc":
-			respondWithJSON(w, 200, chirps)
-		case "desc":
-			slices.Reverse(chirps)
-			respondWithJSON(w, 200, chirps)
-		}
-	})
-	serveMux.HandleFunc("GET /api/chirps/{chirpID}", func(w http.ResponseWriter, req *http.Request) {
-		chirpIDstring := req.PathValue("chirpID")
-		chirpID, err := uuid.Parse(chirpIDstring)
-		if err != nil {
-			respondWithError(w, 400, "Invalid ChirpID")
-			return
-		}
-		dbChirp, err := apiCfg.dbQueries.GetChirp(req.Context(), chirpID)
-		if err != nil {
-			respondWithError(w, 404, "Chirp doesn't exist")
-			return
-		}
-		chirp := Chirp(dbChirp)
-		respondWithJSON(w, 200, chirp)
-	})
+	serveMux.HandleFunc("GET /admin/webhooks", apiCfg.handlerAdminWebhookEventsList)
+	serveMux.HandleFunc("POST /admin/webhooks/{eventID}/replay", apiCfg.handlerAdminWebhookEventReplay)
+	serveMux.HandleFunc("GET /admin/jobs", apiCfg.handlerAdminJobsList)
+	serveMux.HandleFunc("GET /admin/jobs/{jobID}", apiCfg.handlerAdminJobGet)
+	serveMux.HandleFunc("POST /admin/jobs/{jobID}/retry", apiCfg.handlerAdminJobRetry)
+	serveMux.HandleFunc("GET /admin/webhook-endpoints", apiCfg.adminWebhooks(apiCfg.handlerWebhookEndpointsList))
+	serveMux.HandleFunc("POST /admin/webhook-endpoints", apiCfg.adminWebhooks(apiCfg.handlerWebhookEndpointCreate))
+	serveMux.HandleFunc("DELETE /admin/webhook-endpoints/{endpointID}", apiCfg.adminWebhooks(apiCfg.handlerWebhookEndpointDelete))
+	serveMux.HandleFunc("GET /admin/webhook-endpoints/{endpointID}/deliveries", apiCfg.adminWebhooks(apiCfg.handlerWebhookDeliveriesList))
+	serveMux.HandleFunc("POST /admin/webhook-endpoints/{endpointID}/deliveries/{deliveryID}/redeliver", apiCfg.adminWebhooks(apiCfg.handlerWebhookRedeliver))
+	serveMux.HandleFunc("POST /api/chirps", apiCfg.handlerChirpsCreate)
+	serveMux.HandleFunc("GET /api/chirps", apiCfg.handlerChirpsList)
+	serveMux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.handlerChirpsGet)
+	serveMux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handlerChirpsDelete)
+	serveMux.HandleFunc("POST /api/chirps/{chirpID}/poll/vote", apiCfg.handlerPollVote)
+	serveMux.HandleFunc("POST /api/chirps/{chirpID}/pin", apiCfg.handlerPinCreate)
+	serveMux.HandleFunc("DELETE /api/chirps/{chirpID}/pin", apiCfg.handlerPinDelete)
+	serveMux.HandleFunc("PUT /api/pins", apiCfg.handlerPinsReorder)
+	serveMux.HandleFunc("POST /api/chirps/{chirpID}/bookmark", apiCfg.handlerBookmarkCreate)
+	serveMux.HandleFunc("DELETE /api/chirps/{chirpID}/bookmark", apiCfg.handlerBookmarkDelete)
+	serveMux.HandleFunc("GET /api/bookmarks", apiCfg.handlerBookmarksList)
+	serveMux.HandleFunc("GET /api/bookmarks/collections", apiCfg.handlerCollectionsList)
+	serveMux.HandleFunc("POST /api/bookmarks/collections", apiCfg.handlerCollectionsCreate)
+	serveMux.HandleFunc("PUT /api/bookmarks/collections/{collectionID}", apiCfg.handlerCollectionsUpdate)
+	serveMux.HandleFunc("DELETE /api/bookmarks/collections/{collectionID}", apiCfg.handlerCollectionsDelete)
+	serveMux.HandleFunc("GET /api/drafts", apiCfg.handlerDraftsList)
+	serveMux.HandleFunc("POST /api/drafts", apiCfg.handlerDraftsCreate)
+	serveMux.HandleFunc("GET /api/drafts/{draftID}", apiCfg.handlerDraftsGet)
+	serveMux.HandleFunc("PUT /api/drafts/{draftID}", apiCfg.handlerDraftsUpdate)
+	serveMux.HandleFunc("DELETE /api/drafts/{draftID}", apiCfg.handlerDraftsDelete)
+	serveMux.HandleFunc("POST /api/drafts/{draftID}/publish", apiCfg.handlerDraftsPublish)
+	serveMux.HandleFunc("POST /api/media", apiCfg.handlerMediaUpload)
+	serveMux.HandleFunc("GET /api/media/{mediaID}", apiCfg.handlerMediaGet)
+	serveMux.HandleFunc("PUT /api/media/{mediaID}", apiCfg.handlerMediaUpdate)
+	serveMux.HandleFunc("GET /api/media/{mediaID}/file", apiCfg.handlerMediaFile)
+	serveMux.HandleFunc("GET /api/media/{mediaID}/thumbnails/{size}", apiCfg.handlerMediaThumbnail)
 	serveMux.HandleFunc("POST /api/users", func(w http.ResponseWriter, req *http.Request) {
 		userCreds := UserCreds{}
 		decoder := json.NewDecoder(req.Body)
@@ -161,7 +208,7 @@ func main() {
 		newUser.CreatedAt = u.CreatedAt
 		newUser.UpdatedAt = u.UpdatedAt
 		newUser.Email = userCreds.Email
-		newUser.IsChirpyRed = u.IsChirpyRed.Bool
+		newUser.IsChirpyRed = u.IsChirpyRed
 		respondWithJSON(w, 201, newUser)
 	})
 	serveMux.HandleFunc("POST /api/login", func(w http.ResponseWriter, req *http.Request) {
@@ -174,13 +221,16 @@ func main() {
 		}
 		thisUser, err := apiCfg.dbQueries.GetUser(req.Context(), userCreds.Email)
 		if err != nil {
+			apiCfg.metrics.Logins.WithLabelValues("failure").Inc()
 			respondWithError(w, 401, "Incorrect email or password")
 			return
 		}
 		if err = auth.CheckPasswordHash(userCreds.Password, thisUser.HashedPassword); err != nil {
+			apiCfg.metrics.Logins.WithLabelValues("failure").Inc()
 			respondWithError(w, 401, "Incorrect email or password")
 			return
 		}
+		apiCfg.metrics.Logins.WithLabelValues("success").Inc()
 		token, err := auth.MakeJWT(thisUser.ID, apiCfg.secret)
 		if err != nil {
 			respondWithError(w, 500, "Error generating JWT token")
@@ -197,7 +247,7 @@ func main() {
 			CreatedAt:    thisUser.CreatedAt,
 			UpdatedAt:    thisUser.UpdatedAt,
 			Email:        thisUser.Email,
-			IsChirpyRed:  thisUser.IsChirpyRed.Bool,
+			IsChirpyRed:  thisUser.IsChirpyRed,
 			Token:        token,
 			RefreshToken: refreshToken,
 		})
@@ -274,93 +324,95 @@ func main() {
 			CreatedAt:   thisUser.CreatedAt,
 			UpdatedAt:   thisUser.UpdatedAt,
 			Email:       thisUser.Email,
-			IsChirpyRed: thisUser.IsChirpyRed.Bool,
+			IsChirpyRed: thisUser.IsChirpyRed,
 		})
 	})
-	serveMux.HandleFunc("DELETE /api/chirps/{chirpID}", func(w http.ResponseWriter, req *http.Request) {
-		bearerToken, err := auth.GetBearerToken(req.Header)
-		if err != nil {
-			respondWithError(w, 401, "Error fetching authorization token")
-			return
-		}
-		userID, err := auth.ValidateJWT(bearerToken, apiCfg.secret)
-		if err != nil {
-			respondWithError(w, 401, "Invalid authorization token")
-			return
-		}
-		chirpIDstring := req.PathValue("chirpID")
-		chirpID, err := uuid.Parse(chirpIDstring)
-		if err != nil {
-			respondWithError(w, 404, "Chirp not found")
-			return
-		}
-		dbChirp, err := apiCfg.dbQueries.GetChirp(req.Context(), chirpID)
-		if err != nil {
-			respondWithError(w, 404, "Chirp doesn't exist")
-			return
-		}
-		if userID != dbChirp.UserID {
-			respondWithError(w, 403, "You can't delete someone else's chirp")
-			return
-		}
-		err = apiCfg.dbQueries.DeleteChirp(req.Context(), chirpID)
-		if err != nil {
-			respondWithError(w, 500, "Error deleting chirp")
-			return
-		}
-		respondWithJSON(w, 204, nil)
-	})
-	serveMux.HandleFunc("POST /api/polka/webhooks", func(w http.ResponseWriter, req *http.Request) {
-		apiKey, err := auth.GetAPIKey(req.Header)
-		if err != nil {
-			respondWithError(w, 401, "Error fetching API key")
-			return
-		}
-		if apiKey != apiCfg.polkaKey {
-			respondWithError(w, 401, "Invalid API Key")
-			return
-		}
-		webhook := Webhook{}
-		decoder := json.NewDecoder(req.Body)
-		err = decoder.Decode(&webhook)
-		if err != nil {
-			respondWithError(w, 400, "Error decoding request body")
-			return
-		}
-		if webhook.Event != "user.upgraded" {
-			respondWithJSON(w, 204, nil)
-			return
-		}
-		err = apiCfg.dbQueries.UpgradeUserToRed(req.Context(), webhook.Data.UserID)
-		if err != nil {
-			respondWithError(w, 404, "User not found")
-			return
-		}
-		respondWithJSON(w, 204, nil)
-	})
-	err = server.ListenAndServe()
-	if err != nil {
-		fmt.Print(err)
-	}
+	serveMux.HandleFunc("GET /api/entitlements", apiCfg.handlerEntitlementsGet)
+	serveMux.HandleFunc("GET /api/stream", apiCfg.handlerStream)
+	serveMux.HandleFunc("GET /api/ws", apiCfg.handlerWebSocket)
+	serveMux.HandleFunc("POST /api/blocks", apiCfg.handlerBlockCreate)
+	serveMux.HandleFunc("GET /api/blocks", apiCfg.handlerBlocksList)
+	serveMux.HandleFunc("DELETE /api/blocks/{userID}", apiCfg.handlerBlockDelete)
+	serveMux.HandleFunc("POST /api/mutes", apiCfg.handlerMuteCreate)
+	serveMux.HandleFunc("GET /api/mutes", apiCfg.handlerMutesList)
+	serveMux.HandleFunc("DELETE /api/mutes/{userID}", apiCfg.handlerMuteDelete)
+	serveMux.HandleFunc("POST /api/conversations", apiCfg.handlerConversationCreate)
+	serveMux.HandleFunc("GET /api/conversations", apiCfg.handlerConversationsList)
+	serveMux.HandleFunc("GET /api/conversations/unread", apiCfg.handlerConversationsUnread)
+	serveMux.HandleFunc("POST /api/conversations/{conversationID}/messages", apiCfg.handlerMessageCreate)
+	serveMux.HandleFunc("GET /api/conversations/{conversationID}/messages", apiCfg.handlerMessagesList)
+	serveMux.HandleFunc("POST /api/conversations/{conversationID}/read", apiCfg.handlerConversationRead)
+	serveMux.HandleFunc("GET /api/notifications", apiCfg.handlerNotificationsList)
+	serveMux.HandleFunc("GET /api/notifications/unread", apiCfg.handlerNotificationsUnread)
+	serveMux.HandleFunc("POST /api/notifications/read", apiCfg.handlerNotificationsRead)
+	serveMux.HandleFunc("POST /api/notifications/{notificationID}/read", apiCfg.handlerNotificationRead)
+	serveMux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlerPolkaWebhook)
+	serveMux.HandleFunc("GET /api/webhooks", apiCfg.userWebhooks(apiCfg.handlerWebhookEndpointsList))
+	serveMux.HandleFunc("POST /api/webhooks", apiCfg.userWebhooks(apiCfg.handlerWebhookEndpointCreate))
+	serveMux.HandleFunc("DELETE /api/webhooks/{endpointID}", apiCfg.userWebhooks(apiCfg.handlerWebhookEndpointDelete))
+	serveMux.HandleFunc("GET /api/webhooks/{endpointID}/deliveries", apiCfg.userWebhooks(apiCfg.handlerWebhookDeliveriesList))
+	serveMux.HandleFunc("POST /api/webhooks/{endpointID}/deliveries/{deliveryID}/redeliver", apiCfg.userWebhooks(apiCfg.handlerWebhookRedeliver))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+	log.Printf("Serving on %s", server.Addr)
+	<-ctx.Done()
+	stop()
+	log.Print("Shutting down")
+	apiCfg.shutdown(server, stopWorkers, time.Duration(conf.ShutdownTimeoutSeconds)*time.Second)
+	db.Close()
 }
 
 type apiConfig struct {
-	fileserverHits atomic.Int32
-	dbQueries      *database.Queries
-	secret         string
-	polkaKey       string
+	db                 *sql.DB
+	dbQueries          *database.Queries
+	blobs              blobstore.BlobStore
+	chirpEvents        *stream.Broker[stream.Event]
+	notificationEvents *stream.Broker[stream.Notification]
+	platform           string
+	secret             string
+	polkaKey           string
+	adminKey           string
+	polkaSecrets       []string
+	polkaTolerance     time.Duration
+	entitlements       entitlements.Config
+	limiter            *ratelimit.Limiter
+	jobs               *jobs.Registry
+	outboxSinks        []outbox.Sink
+	tokenRetention     time.Duration
+	historyRetention   time.Duration
+	historyGCBatch     int
+	tokenGCBatch       int
+	metrics            *metrics.Metrics
+	heartbeats         *health.Heartbeats
+	schemaVersion      int64
+	// workers tracks background workers and streams tracks WebSocket
+	// connections, for shutdown to wait on. closing is closed when the
+	// server starts shutting down.
+	workers sync.WaitGroup
+	streams sync.WaitGroup
+	closing chan struct{}
 }
 
-type error struct {
+type errorResponse struct {
 	Error string `json:"error"`
 }
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Body       string    `json:"body"`
+	UserID     uuid.UUID `json:"user_id"`
+	Visibility string    `json:"visibility"`
+	Media      []Media   `json:"media"`
+	Poll       *Poll     `json:"poll"`
+	Bookmarked bool      `json:"bookmarked"`
+	Pinned     bool      `json:"pinned"`
 }
 
 type User struct {
@@ -383,21 +435,55 @@ type Token struct {
 }
 
 type Webhook struct {
+	ID    string `json:"id"`
 	Event string `json:"event"`
 	Data  struct {
-		UserID uuid.UUID `json:"user_id"`
+		UserID      uuid.UUID `json:"user_id"`
+		Plan        string    `json:"plan"`
+		PeriodStart time.Time `json:"period_start"`
+		PeriodEnd   time.Time `json:"period_end"`
 	} `json:"data"`
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cfg.fileserverHits.Add(1)
+		cfg.metrics.FileserverHits.Inc()
 		next.ServeHTTP(w, r)
 	})
 }
 
+// requireUser authenticates the request with its bearer token, responding with
+// 401 if that fails.
+func (cfg *apiConfig) requireUser(w http.ResponseWriter, req *http.Request) (uuid.UUID, bool) {
+	bearerToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, 401, "Error fetching authorization token")
+		return uuid.Nil, false
+	}
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization token")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// viewerID returns the user behind an optional bearer token, or uuid.Nil for
+// anonymous requests and invalid tokens.
+func (cfg *apiConfig) viewerID(req *http.Request) uuid.UUID {
+	bearerToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		return uuid.Nil
+	}
+	userID, err := auth.ValidateJWT(bearerToken, cfg.secret)
+	if err != nil {
+		return uuid.Nil
+	}
+	return userID
+}
+
 func respondWithError(w http.ResponseWriter, code int, msg string) {
-	respBody := error{Error: msg}
+	respBody := errorResponse{Error: msg}
 	resp, err := json.Marshal(respBody)
 	if err != nil {
 		log.Printf("Error marshalling JSON: %s", err)
@@ -408,6 +494,27 @@ func respondWithError(w http.ResponseWriter, code int, msg string) {
 	w.Write(resp)
 }
 
+// requestError is an error caused by the request itself, with a message that
+// can be shown to the client as is.
+type requestError struct {
+	code    int
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// respondWithRequestError reports a requestError with its own status code and
+// anything else as a 500.
+func respondWithRequestError(w http.ResponseWriter, err error) {
+	if reqErr := new(requestError); errors.As(err, &reqErr) {
+		respondWithError(w, reqErr.code, reqErr.message)
+		return
+	}
+	respondWithError(w, 500, err.Error())
+}
+
 func respondWithJSON(w http.ResponseWriter, code int, payload any) {
 	resp, err := json.Marshal(payload)
 	if err != nil {
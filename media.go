@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bytes"
+	"chirpy/internal/blobstore"
+	"chirpy/internal/chirptext"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+	"chirpy/internal/imageproc"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxUploadSize    = 5 << 20
+	maxAltTextLength = 1000
+)
+
+var errMediaAttached = &requestError{400, "Media is already attached to a chirp"}
+
+// allowedMediaTypes maps the image types we accept to their file extension.
+// The type is sniffed from the upload itself, never taken from the client.
+var allowedMediaTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+}
+
+type Media struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url"`
+	MimeType  string    `json:"mime_type"`
+	Width     int32     `json:"width"`
+	Height    int32     `json:"height"`
+	SizeBytes int64     `json:"size_bytes"`
+	AltText   string    `json:"alt_text"`
+	// Status is "pending" until the upload has been processed, then "ready"
+	// or "failed".
+	Status     string           `json:"status"`
+	BlurHash   *string          `json:"blurhash"`
+	Thumbnails []MediaThumbnail `json:"thumbnails"`
+}
+
+type MediaThumbnail struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type"`
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
+}
+
+type AltTextRequest struct {
+	AltText string `json:"alt_text"`
+}
+
+func mediaFromDB(m database.Media, variants []database.MediaVariant) Media {
+	media := Media{
+		ID:         m.ID,
+		URL:        "/api/media/" + m.ID.String() + "/file",
+		MimeType:   m.MimeType,
+		Width:      m.Width,
+		Height:     m.Height,
+		SizeBytes:  m.SizeBytes,
+		AltText:    m.AltText,
+		Status:     m.Status,
+		Thumbnails: []MediaThumbnail{},
+	}
+	if m.Blurhash.Valid {
+		media.BlurHash = &m.Blurhash.String
+	}
+	for _, v := range variants {
+		media.Thumbnails = append(media.Thumbnails, MediaThumbnail{
+			Name:     v.Name,
+			URL:      "/api/media/" + m.ID.String() + "/thumbnails/" + v.Name,
+			MimeType: v.MimeType,
+			Width:    v.Width,
+			Height:   v.Height,
+		})
+	}
+	return media
+}
+
+// renderMedia converts media to their JSON form, loading the thumbnails of
+// all of them in one query.
+func (cfg *apiConfig) renderMedia(ctx context.Context, dbMedia []database.Media) ([]Media, error) {
+	ids := make([]uuid.UUID, len(dbMedia))
+	for i := range dbMedia {
+		ids[i] = dbMedia[i].ID
+	}
+	rows, err := cfg.dbQueries.ListMediaVariants(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	variants := map[uuid.UUID][]database.MediaVariant{}
+	for _, v := range rows {
+		variants[v.MediaID] = append(variants[v.MediaID], v)
+	}
+	media := make([]Media, len(dbMedia))
+	for i, m := range dbMedia {
+		media[i] = mediaFromDB(m, variants[m.ID])
+	}
+	return media, nil
+}
+
+// newBlobStore builds the media store selected by MEDIA_STORAGE: "fs" (the
+// default) keeps files under MEDIA_DIR, "s3" uses an S3-compatible bucket.
+func newBlobStore(conf config.Config) (blobstore.BlobStore, error) {
+	switch conf.MediaStorage {
+	case "fs":
+		return blobstore.NewFS(conf.MediaDir)
+	case "s3":
+		return blobstore.NewS3(blobstore.S3Config{
+			Endpoint:        conf.S3Endpoint,
+			Region:          conf.S3Region,
+			Bucket:          conf.S3Bucket,
+			AccessKeyID:     conf.S3AccessKeyID,
+			SecretAccessKey: conf.S3SecretAccessKey,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown MEDIA_STORAGE %q", conf.MediaStorage)
+	}
+}
+
+func (cfg *apiConfig) handlerMediaUpload(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	if !cfg.rateLimit(w, req, userID) {
+		return
+	}
+	req.Body = http.MaxBytesReader(w, req.Body, maxUploadSize+(1<<20))
+	file, header, err := req.FormFile("file")
+	if err != nil {
+		if maxBytesErr := new(http.MaxBytesError); errors.As(err, &maxBytesErr) {
+			respondWithError(w, 413, "Upload is too large")
+			return
+		}
+		respondWithError(w, 400, "Error reading upload")
+		return
+	}
+	defer file.Close()
+	if header.Size > maxUploadSize {
+		respondWithError(w, 413, "Upload is too large")
+		return
+	}
+	altText := req.FormValue("alt_text")
+	if chirptext.Length(altText) > maxAltTextLength {
+		respondWithError(w, 400, "Alt text is too long")
+		return
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		respondWithError(w, 400, "Error reading upload")
+		return
+	}
+	mimeType, ok := imageproc.Sniff(data)
+	if !ok {
+		respondWithError(w, 415, "Unsupported media type")
+		return
+	}
+	imgCfg, err := imageproc.Check(data)
+	if errors.Is(err, imageproc.ErrTooLarge) {
+		respondWithError(w, 413, "Image dimensions are too large")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 400, "Invalid image")
+		return
+	}
+	// The raw upload is kept aside until a media worker has stripped its
+	// metadata; it's never served as is.
+	mediaID := uuid.New()
+	key := "uploads/" + mediaID.String()
+	if err = cfg.blobs.Put(req.Context(), key, bytes.NewReader(data), int64(len(data)), mimeType); err != nil {
+		respondWithError(w, 500, "Error storing upload")
+		return
+	}
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+	if err != nil {
+		cfg.removeBlob(req.Context(), key)
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	m, err := qtx.CreateMedia(req.Context(), database.CreateMediaParams{
+		ID:         mediaID,
+		UserID:     userID,
+		StorageKey: key,
+		MimeType:   mimeType,
+		SizeBytes:  int64(len(data)),
+		Width:      int32(imgCfg.Width),
+		Height:     int32(imgCfg.Height),
+		AltText:    altText,
+	})
+	if err == nil {
+		err = enqueueMediaJob(req.Context(), qtx, m.ID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		cfg.removeBlob(req.Context(), key)
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	respondWithJSON(w, 202, mediaFromDB(m, nil))
+}
+
+func (cfg *apiConfig) handlerMediaGet(w http.ResponseWriter, req *http.Request) {
+	mediaID, err := uuid.Parse(req.PathValue("mediaID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid MediaID")
+		return
+	}
+	m, err := cfg.dbQueries.GetMedia(req.Context(), mediaID)
+	if err != nil {
+		respondWithError(w, 404, "Media doesn't exist")
+		return
+	}
+	if _, ok := cfg.authorizeMedia(w, req, m); !ok {
+		return
+	}
+	media, err := cfg.renderMedia(req.Context(), []database.Media{m})
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	respondWithJSON(w, 200, media[0])
+}
+
+func (cfg *apiConfig) handlerMediaUpdate(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	mediaID, err := uuid.Parse(req.PathValue("mediaID"))
+	if err != nil {
+		respondWithError(w, 404, "Media not found")
+		return
+	}
+	altReq := AltTextRequest{}
+	decoder := json.NewDecoder(req.Body)
+	if err = decoder.Decode(&altReq); err != nil {
+		respondWithError(w, 400, "Error decoding request body")
+		return
+	}
+	if chirptext.Length(altReq.AltText) > maxAltTextLength {
+		respondWithError(w, 400, "Alt text is too long")
+		return
+	}
+	m, err := cfg.dbQueries.UpdateMediaAltText(req.Context(), database.UpdateMediaAltTextParams{ID: mediaID, UserID: userID, AltText: altReq.AltText})
+	if err != nil {
+		respondWithError(w, 404, "Media not found")
+		return
+	}
+	media, err := cfg.renderMedia(req.Context(), []database.Media{m})
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	respondWithJSON(w, 200, media[0])
+}
+
+func (cfg *apiConfig) handlerMediaFile(w http.ResponseWriter, req *http.Request) {
+	mediaID, err := uuid.Parse(req.PathValue("mediaID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid MediaID")
+		return
+	}
+	m, err := cfg.dbQueries.GetMedia(req.Context(), mediaID)
+	if err != nil {
+		respondWithError(w, 404, "Media doesn't exist")
+		return
+	}
+	public, ok := cfg.authorizeMedia(w, req, m)
+	if !ok {
+		return
+	}
+	switch m.Status {
+	case "ready":
+		cfg.serveBlob(w, req, m.StorageKey, m.MimeType, public)
+	case "failed":
+		respondWithError(w, 404, "Media processing failed")
+	default:
+		respondWithError(w, 404, "Media is still processing")
+	}
+}
+
+func (cfg *apiConfig) handlerMediaThumbnail(w http.ResponseWriter, req *http.Request) {
+	mediaID, err := uuid.Parse(req.PathValue("mediaID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid MediaID")
+		return
+	}
+	m, err := cfg.dbQueries.GetMedia(req.Context(), mediaID)
+	if err != nil {
+		respondWithError(w, 404, "Media doesn't exist")
+		return
+	}
+	public, ok := cfg.authorizeMedia(w, req, m)
+	if !ok {
+		return
+	}
+	v, err := cfg.dbQueries.GetMediaVariant(req.Context(), database.GetMediaVariantParams{MediaID: mediaID, Name: req.PathValue("size")})
+	if err != nil {
+		respondWithError(w, 404, "Thumbnail doesn't exist")
+		return
+	}
+	cfg.serveBlob(w, req, v.StorageKey, v.MimeType, public)
+}
+
+// authorizeMedia checks the viewer may see m, responding with 404 if not.
+// Media attached to a chirp is visible to whoever can see the chirp by ID;
+// unattached media only to its uploader. It returns whether anyone may see
+// it, in which case shared caches may keep it, and whether the viewer may.
+func (cfg *apiConfig) authorizeMedia(w http.ResponseWriter, req *http.Request, m database.Media) (bool, bool) {
+	viewerID := cfg.viewerID(req)
+	chirp, err := cfg.dbQueries.GetChirpForMedia(req.Context(), m.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		if viewerID == uuid.Nil || viewerID != m.UserID {
+			respondWithError(w, 404, "Media doesn't exist")
+			return false, false
+		}
+		return false, true
+	}
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return false, false
+	}
+	if !canView(chirp, viewerID, true) {
+		respondWithError(w, 404, "Media doesn't exist")
+		return false, false
+	}
+	return canView(chirp, uuid.Nil, true), true
+}
+
+// serveBlob streams a stored file. Keys never change once written, so the
+// response can be cached forever, by shared caches too if it's public.
+func (cfg *apiConfig) serveBlob(w http.ResponseWriter, req *http.Request, key, mimeType string, public bool) {
+	blob, err := cfg.blobs.Get(req.Context(), key)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			respondWithError(w, 404, "Media doesn't exist")
+			return
+		}
+		respondWithError(w, 500, "Error reading media")
+		return
+	}
+	defer blob.Close()
+	w.Header().Set("Content-Type", mimeType)
+	cacheControl := "private, max-age=31536000, immutable"
+	if public {
+		cacheControl = "public, max-age=31536000, immutable"
+	}
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(200)
+	io.Copy(w, blob)
+}
+
+// attachableMedia checks that the media IDs sent with a new chirp belong to
+// the author and aren't used by another chirp yet. maxMedia is the author's
+// limit on attachments.
+func (cfg *apiConfig) attachableMedia(ctx context.Context, userID uuid.UUID, mediaIDs []uuid.UUID, maxMedia int) error {
+	if len(mediaIDs) > maxMedia {
+		return &requestError{400, fmt.Sprintf("A chirp can have at most %d media attachments", maxMedia)}
+	}
+	seen := map[uuid.UUID]bool{}
+	for _, mediaID := range mediaIDs {
+		if seen[mediaID] {
+			return &requestError{400, "Duplicate media attachment"}
+		}
+		seen[mediaID] = true
+		m, err := cfg.dbQueries.GetMedia(ctx, mediaID)
+		if err != nil || m.UserID != userID {
+			return &requestError{400, "Media not found"}
+		}
+		if m.Status == "failed" {
+			return &requestError{400, "Media processing failed"}
+		}
+		attached, err := cfg.dbQueries.IsMediaAttached(ctx, mediaID)
+		if err != nil {
+			return err
+		}
+		if attached {
+			return errMediaAttached
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"chirpy/internal/database"
+	"chirpy/internal/imageproc"
+	"chirpy/internal/jobs"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+var errInvalidMedia = errors.New("upload isn't a valid image")
+
+// MediaJob is the payload of media processing jobs.
+type MediaJob struct {
+	MediaID uuid.UUID `json:"media_id"`
+}
+
+// enqueueMediaJob queues the processing of an upload. q should be bound to
+// the transaction creating the media row.
+func enqueueMediaJob(ctx context.Context, q *database.Queries, mediaID uuid.UUID) error {
+	_, err := enqueueJob(ctx, q, jobProcessMedia, MediaJob{MediaID: mediaID}, jobOptions{UniqueKey: mediaID.String()})
+	return err
+}
+
+// processMediaJob is the job form of processMedia. Uploads that aren't
+// valid images won't get any better, so they aren't retried.
+func (cfg *apiConfig) processMediaJob(ctx context.Context, p MediaJob) error {
+	err := cfg.processMedia(ctx, p.MediaID)
+	if errors.Is(err, errInvalidMedia) {
+		return jobs.Permanent(err)
+	}
+	return err
+}
+
+// processMedia strips metadata from an upload, stores it along with its
+// thumbnails and marks it ready. Uploads that aren't valid images are marked
+// failed and errInvalidMedia returned. The raw upload is removed either way.
+func (cfg *apiConfig) processMedia(ctx context.Context, mediaID uuid.UUID) error {
+	m, err := cfg.dbQueries.ClaimMedia(ctx, mediaID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Already processed.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	blob, err := cfg.blobs.Get(ctx, m.StorageKey)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(blob, maxUploadSize+1))
+	blob.Close()
+	if err != nil {
+		return err
+	}
+
+	result, err := imageproc.Process(data)
+	if err != nil {
+		if markErr := cfg.dbQueries.MarkMediaFailed(ctx, m.ID); markErr != nil {
+			return markErr
+		}
+		cfg.removeBlob(ctx, m.StorageKey)
+		return fmt.Errorf("%w: %w", errInvalidMedia, err)
+	}
+
+	prefix := "media/" + m.ID.String() + "/"
+	originalKey := prefix + "original" + allowedMediaTypes[result.Original.MimeType]
+	if err = cfg.putVariant(ctx, originalKey, result.Original); err != nil {
+		return err
+	}
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	for _, thumb := range result.Thumbnails {
+		key := prefix + thumb.Name + allowedMediaTypes[thumb.MimeType]
+		if err = cfg.putVariant(ctx, key, thumb); err != nil {
+			return err
+		}
+		err = qtx.CreateMediaVariant(ctx, database.CreateMediaVariantParams{
+			MediaID:    m.ID,
+			Name:       thumb.Name,
+			StorageKey: key,
+			MimeType:   thumb.MimeType,
+			SizeBytes:  int64(len(thumb.Data)),
+			Width:      int32(thumb.Width),
+			Height:     int32(thumb.Height),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	_, err = qtx.MarkMediaReady(ctx, database.MarkMediaReadyParams{
+		ID:         m.ID,
+		StorageKey: originalKey,
+		MimeType:   result.Original.MimeType,
+		SizeBytes:  int64(len(result.Original.Data)),
+		Width:      int32(result.Original.Width),
+		Height:     int32(result.Original.Height),
+		Blurhash:   sql.NullString{String: result.BlurHash, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	cfg.removeBlob(ctx, m.StorageKey)
+	return nil
+}
+
+func (cfg *apiConfig) putVariant(ctx context.Context, key string, v imageproc.Variant) error {
+	err := cfg.blobs.Put(ctx, key, bytes.NewReader(v.Data), int64(len(v.Data)), v.MimeType)
+	if err != nil {
+		return fmt.Errorf("storing %s: %w", key, err)
+	}
+	return nil
+}
+
+func (cfg *apiConfig) removeBlob(ctx context.Context, key string) {
+	if err := cfg.blobs.Delete(ctx, key); err != nil {
+		log.Printf("Error removing blob %s: %s", key, err)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/notifications"
+	"chirpy/internal/pagination"
+	"chirpy/internal/stream"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+type NotificationPage struct {
+	Notifications []notifications.Group `json:"notifications"`
+	NextCursor    string                `json:"next_cursor,omitempty"`
+}
+
+type NotificationsReadRequest struct {
+	IDs []uuid.UUID `json:"ids"`
+}
+
+// notify records a notification for userID and pushes it to their live
+// connections. Notifications caused by the user themselves or by someone
+// they're blocking (or blocked by) are dropped.
+func notify(ctx context.Context, q *database.Queries, userID uuid.UUID, notificationType string, actorID, chirpID uuid.NullUUID) error {
+	if actorID.Valid {
+		if actorID.UUID == userID {
+			return nil
+		}
+		blocked, err := q.IsBlockedBetween(ctx, database.IsBlockedBetweenParams{UserA: userID, UserB: actorID.UUID})
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return nil
+		}
+	}
+	n, err := q.CreateNotification(ctx, database.CreateNotificationParams{
+		UserID:  userID,
+		Type:    notificationType,
+		ActorID: actorID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		return err
+	}
+	event := stream.Notification{
+		ID:        n.ID,
+		CreatedAt: n.CreatedAt,
+		UserID:    n.UserID,
+		Type:      n.Type,
+		Message:   notifications.Message(n.Type, 1),
+	}
+	if n.ActorID.Valid {
+		event.ActorID = &n.ActorID.UUID
+	}
+	if n.ChirpID.Valid {
+		event.ChirpID = &n.ChirpID.UUID
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return q.NotifyNotificationEvent(ctx, string(payload))
+}
+
+func (cfg *apiConfig) handlerNotificationsList(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	limit, err := pagination.Limit(req.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	params := database.ListNotificationsParams{UserID: userID, MaxResults: int32(limit)}
+	if c := req.URL.Query().Get("cursor"); c != "" {
+		cursor, err := pagination.Decode(c)
+		if err != nil {
+			respondWithError(w, 400, err.Error())
+			return
+		}
+		params.BeforeCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	dbNotifications, err := cfg.dbQueries.ListNotifications(req.Context(), params)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	ns := make([]notifications.Notification, len(dbNotifications))
+	for i, n := range dbNotifications {
+		ns[i] = notifications.Notification{
+			ID:        n.ID,
+			CreatedAt: n.CreatedAt,
+			Type:      n.Type,
+			ActorID:   n.ActorID,
+			ChirpID:   n.ChirpID,
+			Read:      n.ReadAt.Valid,
+		}
+	}
+	page := NotificationPage{Notifications: notifications.GroupNotifications(ns)}
+	if len(dbNotifications) == limit {
+		last := dbNotifications[len(dbNotifications)-1]
+		page.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	respondWithJSON(w, 200, page)
+}
+
+func (cfg *apiConfig) handlerNotificationsUnread(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	count, err := cfg.dbQueries.CountUnreadNotifications(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	respondWithJSON(w, 200, UnreadCount{UnreadCount: count})
+}
+
+// handlerNotificationsRead marks the given notifications as read, or all of
+// them when no IDs are sent.
+func (cfg *apiConfig) handlerNotificationsRead(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	readReq := NotificationsReadRequest{}
+	if req.ContentLength != 0 {
+		decoder := json.NewDecoder(req.Body)
+		if err := decoder.Decode(&readReq); err != nil {
+			respondWithError(w, 400, "Error decoding request body")
+			return
+		}
+	}
+	var err error
+	if len(readReq.IDs) == 0 {
+		err = cfg.dbQueries.MarkAllNotificationsRead(req.Context(), userID)
+	} else {
+		err = cfg.dbQueries.MarkNotificationsRead(req.Context(), database.MarkNotificationsReadParams{UserID: userID, Ids: readReq.IDs})
+	}
+	if err != nil {
+		respondWithError(w, 500, "Error marking notifications as read")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
+
+func (cfg *apiConfig) handlerNotificationRead(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	notificationID, err := uuid.Parse(req.PathValue("notificationID"))
+	if err != nil {
+		respondWithError(w, 404, "Notification not found")
+		return
+	}
+	err = cfg.dbQueries.MarkNotificationsRead(req.Context(), database.MarkNotificationsReadParams{UserID: userID, Ids: []uuid.UUID{notificationID}})
+	if err != nil {
+		respondWithError(w, 500, "Error marking notification as read")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
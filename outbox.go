@@ -0,0 +1,166 @@
+package main
+
+import (
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+	"chirpy/internal/jobs"
+	"chirpy/internal/outbox"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	outboxPollInterval = time.Second
+	outboxBatchSize    = 100
+	outboxLease        = 5 * time.Minute
+	// maxOutboxAttempts gives a sink over 16 hours to recover, given
+	// jobs.Backoff, before an event is given up on.
+	maxOutboxAttempts = 25
+)
+
+// Outbox event types.
+const (
+	outboxChirpCreated        = "chirp.created"
+	outboxChirpDeleted        = "chirp.deleted"
+	outboxSubscriptionUpdated = "user.subscription_updated"
+)
+
+// SubscriptionData is the data of subscription outbox events.
+type SubscriptionData struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Plan        string    `json:"plan"`
+	Status      string    `json:"status"`
+	PeriodEnd   time.Time `json:"period_end"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+}
+
+// recordOutbox adds an event to the outbox. q must be bound to the
+// transaction making the change it describes, so the event is published if
+// and only if the change is committed.
+func recordOutbox(ctx context.Context, q *database.Queries, aggregateType string, aggregateID uuid.UUID, eventType string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return q.CreateOutboxMessage(ctx, database.CreateOutboxMessageParams{
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       payload,
+	})
+}
+
+// outboxSinks builds the sinks named in OUTBOX_SINKS, a comma-separated list
+// of log, webhook and nats. It defaults to none.
+func outboxSinks(conf config.Config) ([]outbox.Sink, error) {
+	sinks := []outbox.Sink{}
+	for _, name := range conf.OutboxSinks {
+		switch name {
+		case "log":
+			sinks = append(sinks, outbox.NewLogSink(log.Default()))
+		case "webhook":
+			sinks = append(sinks, outbox.NewWebhookSink(conf.OutboxWebhookURL, conf.OutboxWebhookSecret, &http.Client{Timeout: webhookTimeout}))
+		case "nats":
+			sink, err := outbox.NewNATSSink(conf.OutboxNATSURL, conf.OutboxNATSSubjectPrefix)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		default:
+			return nil, fmt.Errorf("unknown outbox sink %q", name)
+		}
+	}
+	return sinks, nil
+}
+
+// runOutboxRelay publishes outbox events to every sink until ctx is done.
+// Delivery is at least once: an event is marked published only after every
+// sink accepted it, so a failure or crash in between publishes it again.
+// Events still failing after maxOutboxAttempts are marked failed and not
+// retried. Both kinds are removed by collectHistory.
+func (cfg *apiConfig) runOutboxRelay(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+	for {
+		for ctx.Err() == nil {
+			cfg.heartbeats.Beat("outbox", outboxPollInterval, time.Now())
+			claimed, err := cfg.relayOutbox(context.WithoutCancel(ctx))
+			if err != nil {
+				log.Printf("Error relaying outbox: %s", err)
+				break
+			}
+			if claimed < outboxBatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relayOutbox publishes one batch of due events and returns how many it
+// claimed. The batch is leased for outboxLease rather than kept locked, so
+// no transaction stays open while sinks are called; events the relay doesn't
+// get to before the lease runs out are left for the next claim. Events that
+// fail are retried with backoff, which may let later events overtake them.
+func (cfg *apiConfig) relayOutbox(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	leaseUntil := now.Add(outboxLease)
+	batch, err := cfg.dbQueries.ClaimOutboxBatch(ctx, database.ClaimOutboxBatchParams{
+		LockedUntil: leaseUntil,
+		Now:         now,
+		MaxResults:  outboxBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+	publishCtx, cancel := context.WithDeadline(ctx, leaseUntil)
+	defer cancel()
+	for _, row := range batch {
+		if publishCtx.Err() != nil {
+			break
+		}
+		m := outbox.Message{
+			ID:            row.ID,
+			Type:          row.EventType,
+			AggregateType: row.AggregateType,
+			AggregateID:   row.AggregateID,
+			CreatedAt:     row.CreatedAt,
+			Data:          row.Payload,
+		}
+		if err = cfg.publishOutbox(publishCtx, m); err != nil {
+			log.Printf("Error publishing outbox event %s: %s", row.ID, err)
+			err = cfg.dbQueries.RecordOutboxFailure(ctx, database.RecordOutboxFailureParams{
+				ID:            row.ID,
+				LastError:     sql.NullString{String: err.Error(), Valid: true},
+				NextAttemptAt: time.Now().UTC().Add(jobs.Backoff(int(row.Attempts) + 1)),
+				MaxAttempts:   maxOutboxAttempts,
+			})
+		} else {
+			err = cfg.dbQueries.MarkOutboxPublished(ctx, row.ID)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(batch), nil
+}
+
+func (cfg *apiConfig) publishOutbox(ctx context.Context, m outbox.Message) error {
+	for _, sink := range cfg.outboxSinks {
+		if err := sink.Publish(ctx, m); err != nil {
+			return fmt.Errorf("%s sink: %w", sink.Name(), err)
+		}
+	}
+	return nil
+}
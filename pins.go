@@ -0,0 +1,197 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+type PinsReorderRequest struct {
+	ChirpIDs []uuid.UUID `json:"chirp_ids"`
+}
+
+// splitPinned separates an author's pinned chirps, in pin order, from the
+// rest of their chirps. Only pinned chirps present in dbChirps are returned.
+func (cfg *apiConfig) splitPinned(ctx context.Context, authorID uuid.UUID, dbChirps []database.Chirp) ([]database.Chirp, []database.Chirp, error) {
+	rows, err := cfg.dbQueries.ListPinnedChirps(ctx, authorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	pinned := []database.Chirp{}
+	for _, row := range rows {
+		if slices.ContainsFunc(dbChirps, func(c database.Chirp) bool { return c.ID == row.Chirp.ID }) {
+			pinned = append(pinned, row.Chirp)
+		}
+	}
+	rest := slices.DeleteFunc(slices.Clone(dbChirps), func(c database.Chirp) bool {
+		return slices.ContainsFunc(pinned, func(p database.Chirp) bool { return p.ID == c.ID })
+	})
+	return pinned, rest, nil
+}
+
+// handlerPinCreate pins one of the user's chirps after the ones already
+// pinned. Chirpy Red members can pin more chirps; pins over the limit after
+// a downgrade are kept, but no new ones can be added.
+func (cfg *apiConfig) handlerPinCreate(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	dbChirp, err := cfg.dbQueries.GetChirp(req.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, 404, "Chirp doesn't exist")
+		return
+	}
+	if dbChirp.UserID != userID {
+		respondWithError(w, 403, "You can't pin someone else's chirp")
+		return
+	}
+	user, err := cfg.dbQueries.GetUserByID(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 401, "User not found")
+		return
+	}
+	caps, err := cfg.capabilities(req.Context(), user)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	maxPins := caps.MaxPins
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	// Serializes pin changes per user so concurrent requests can't go over
+	// the limit.
+	if err = qtx.LockUser(req.Context(), userID); err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	count, err := qtx.CountPinnedChirps(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	pinned, err := qtx.PinChirp(req.Context(), database.PinChirpParams{UserID: userID, ChirpID: chirpID})
+	if err != nil {
+		respondWithError(w, 500, "Error pinning chirp")
+		return
+	}
+	if pinned > 0 && count >= int64(maxPins) {
+		respondWithError(w, 400, fmt.Sprintf("You can pin at most %d chirps", maxPins))
+		return
+	}
+	if err = tx.Commit(); err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	chirps, err := cfg.renderChirps(req.Context(), userID, []database.Chirp{dbChirp})
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	respondWithJSON(w, 200, chirps[0])
+}
+
+func (cfg *apiConfig) handlerPinDelete(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	unpinned, err := cfg.dbQueries.UnpinChirp(req.Context(), database.UnpinChirpParams{UserID: userID, ChirpID: chirpID})
+	if err != nil {
+		respondWithError(w, 500, "Error unpinning chirp")
+		return
+	}
+	if unpinned == 0 {
+		respondWithError(w, 404, "Chirp isn't pinned")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
+
+// handlerPinsReorder sets the order of the user's pinned chirps. The request
+// must list every pinned chirp exactly once.
+func (cfg *apiConfig) handlerPinsReorder(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	reorderReq := PinsReorderRequest{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&reorderReq); err != nil {
+		respondWithError(w, 400, "Error decoding request body")
+		return
+	}
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	if err = qtx.LockUser(req.Context(), userID); err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	count, err := qtx.CountPinnedChirps(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	unique := slices.Clone(reorderReq.ChirpIDs)
+	slices.SortFunc(unique, func(a, b uuid.UUID) int { return slices.Compare(a[:], b[:]) })
+	unique = slices.Compact(unique)
+	if len(unique) != len(reorderReq.ChirpIDs) || int64(len(unique)) != count {
+		respondWithError(w, 400, "chirp_ids must list every pinned chirp exactly once")
+		return
+	}
+	for i, chirpID := range reorderReq.ChirpIDs {
+		updated, err := qtx.SetPinPosition(req.Context(), database.SetPinPositionParams{UserID: userID, ChirpID: chirpID, Position: int32(i)})
+		if err != nil {
+			respondWithError(w, 500, err.Error())
+			return
+		}
+		if updated == 0 {
+			respondWithError(w, 400, "chirp_ids must list every pinned chirp exactly once")
+			return
+		}
+	}
+	rows, err := qtx.ListPinnedChirps(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	if err = tx.Commit(); err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	dbChirps := make([]database.Chirp, len(rows))
+	for i, row := range rows {
+		dbChirps[i] = row.Chirp
+	}
+	chirps, err := cfg.renderChirps(req.Context(), userID, dbChirps)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	respondWithJSON(w, 200, chirps)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/notifications"
+	"chirpy/internal/subscriptions"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const maxWebhookSize = 1 << 20
+
+const webhookProviderPolka = "polka"
+
+// Processing states of a received webhook event.
+const (
+	webhookReceived  = "received"
+	webhookProcessed = "processed"
+	webhookIgnored   = "ignored"
+	webhookFailed    = "failed"
+)
+
+var errUserNotFound = errors.New("user not found")
+
+// polkaEventID identifies a Polka event for deduplication: its id field, the
+// X-Polka-Event-ID header, or failing both a hash of the raw body.
+func polkaEventID(webhook Webhook, headers http.Header, body []byte) string {
+	if webhook.ID != "" {
+		return webhook.ID
+	}
+	if id := headers.Get("X-Polka-Event-ID"); id != "" {
+		return id
+	}
+	sum := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// handlerPolkaWebhook applies Polka payment events. Requests must carry
+// POLKA_KEY and, when POLKA_WEBHOOK_SECRETS is set, an HMAC signature of the
+// raw body made with one of those secrets. Every event is logged in
+// webhook_events before it's applied, so retries of an event that was
+// already processed are acknowledged without applying it twice.
+func (cfg *apiConfig) handlerPolkaWebhook(w http.ResponseWriter, req *http.Request) {
+	apiKey, err := auth.GetAPIKey(req.Header)
+	if err != nil {
+		respondWithError(w, 401, "Error fetching API key")
+		return
+	}
+	if !auth.CompareAPIKey(apiKey, cfg.polkaKey) {
+		respondWithError(w, 401, "Invalid API Key")
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxWebhookSize))
+	if err != nil {
+		respondWithError(w, 400, "Error reading request body")
+		return
+	}
+	if len(cfg.polkaSecrets) > 0 {
+		err = auth.VerifyWebhookSignature(req.Header, body, cfg.polkaSecrets, cfg.polkaTolerance, time.Now())
+		if errors.Is(err, auth.ErrSignatureExpired) {
+			respondWithError(w, 401, "Webhook timestamp is too old")
+			return
+		}
+		if err != nil {
+			respondWithError(w, 401, "Invalid webhook signature")
+			return
+		}
+	}
+	webhook := Webhook{}
+	if err = json.Unmarshal(body, &webhook); err != nil {
+		respondWithError(w, 400, "Error decoding request body")
+		return
+	}
+	eventID := polkaEventID(webhook, req.Header, body)
+	ev, err := cfg.dbQueries.CreateWebhookEvent(req.Context(), database.CreateWebhookEventParams{
+		Provider:  webhookProviderPolka,
+		EventID:   eventID,
+		EventType: webhook.Event,
+		Payload:   body,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		ev, err = cfg.dbQueries.GetWebhookEventByEventID(req.Context(), database.GetWebhookEventByEventIDParams{Provider: webhookProviderPolka, EventID: eventID})
+	}
+	if err != nil {
+		respondWithError(w, 500, "Error recording webhook event")
+		return
+	}
+	if ev.Status == webhookProcessed || ev.Status == webhookIgnored {
+		cfg.metrics.WebhookEvents.WithLabelValues(webhookProviderPolka, "duplicate").Inc()
+		respondWithJSON(w, 204, nil)
+		return
+	}
+	err = cfg.processPolkaEvent(req.Context(), ev.ID)
+	if errors.Is(err, errUserNotFound) {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Error processing webhook event")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
+
+// processPolkaEvent applies a logged Polka event unless it has been already.
+// The event row stays locked while it's applied so concurrent deliveries and
+// replays can't apply it twice. Failures are recorded on the event.
+func (cfg *apiConfig) processPolkaEvent(ctx context.Context, id uuid.UUID) error {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	ev, err := qtx.LockWebhookEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+	if ev.Status == webhookProcessed || ev.Status == webhookIgnored {
+		return nil
+	}
+	webhook := Webhook{}
+	if err = json.Unmarshal(ev.Payload, &webhook); err != nil {
+		return err
+	}
+	applied := false
+	switch webhook.Event {
+	case subscriptions.EventUpgraded, subscriptions.EventDowngraded, subscriptions.EventRenewed, subscriptions.EventPaymentFailed:
+		applied, err = applySubscriptionEvent(ctx, qtx, webhook, time.Now().UTC())
+	}
+	status := webhookProcessed
+	if !applied {
+		status = webhookIgnored
+	}
+	if err == nil {
+		err = qtx.MarkWebhookEventProcessed(ctx, database.MarkWebhookEventProcessedParams{ID: ev.ID, Status: status})
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		tx.Rollback()
+		cfg.metrics.WebhookEvents.WithLabelValues(webhookProviderPolka, webhookFailed).Inc()
+		markErr := cfg.dbQueries.MarkWebhookEventFailed(ctx, database.MarkWebhookEventFailedParams{
+			ID:    ev.ID,
+			Error: sql.NullString{String: err.Error(), Valid: true},
+		})
+		if markErr != nil {
+			log.Printf("Error recording failure of webhook event %s: %s", ev.ID, markErr)
+		}
+		return err
+	}
+	cfg.metrics.WebhookEvents.WithLabelValues(webhookProviderPolka, status).Inc()
+	if applied && webhook.Event == subscriptions.EventUpgraded {
+		err = notify(ctx, cfg.dbQueries, webhook.Data.UserID, notifications.TypeChirpyRedUpgraded, uuid.NullUUID{}, uuid.NullUUID{})
+		if err != nil {
+			log.Printf("Error notifying user %s of upgrade: %s", webhook.Data.UserID, err)
+		}
+	}
+	return nil
+}
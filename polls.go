@@ -0,0 +1,166 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/polls"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PollRequest struct {
+	Options   []string  `json:"options"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type PollVoteRequest struct {
+	Option int `json:"option"`
+}
+
+type Poll struct {
+	ExpiresAt  time.Time    `json:"expires_at"`
+	Closed     bool         `json:"closed"`
+	TotalVotes int64        `json:"total_votes"`
+	Options    []PollOption `json:"options"`
+	// VotedOption is the index of the viewer's vote, if they voted.
+	VotedOption *int `json:"voted_option"`
+	Voted       bool `json:"voted"`
+}
+
+type PollOption struct {
+	Text       string `json:"text"`
+	Votes      int64  `json:"votes"`
+	Percentage int    `json:"percentage"`
+}
+
+// createPoll stores the poll attached to a new chirp.
+func createPoll(ctx context.Context, q *database.Queries, chirpID uuid.UUID, pollReq PollRequest) error {
+	err := q.CreatePoll(ctx, database.CreatePollParams{ChirpID: chirpID, ExpiresAt: pollReq.ExpiresAt.UTC()})
+	if err != nil {
+		return err
+	}
+	for i, option := range pollReq.Options {
+		err = q.CreatePollOption(ctx, database.CreatePollOptionParams{ChirpID: chirpID, Position: int32(i), Text: strings.TrimSpace(option)})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderPolls loads the polls attached to the given chirps along with their
+// results and the viewer's votes.
+func (cfg *apiConfig) renderPolls(ctx context.Context, viewerID uuid.UUID, chirpIDs []uuid.UUID) (map[uuid.UUID]*Poll, error) {
+	dbPolls, err := cfg.dbQueries.ListPollsForChirps(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	result := map[uuid.UUID]*Poll{}
+	if len(dbPolls) == 0 {
+		return result, nil
+	}
+	ids := make([]uuid.UUID, len(dbPolls))
+	for i, p := range dbPolls {
+		ids[i] = p.ChirpID
+		result[p.ChirpID] = &Poll{
+			ExpiresAt: p.ExpiresAt,
+			Closed:    !time.Now().Before(p.ExpiresAt),
+			Options:   []PollOption{},
+		}
+	}
+	options, err := cfg.dbQueries.ListPollResults(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range options {
+		poll := result[o.ChirpID]
+		poll.Options = append(poll.Options, PollOption{Text: o.Text, Votes: o.Votes})
+		poll.TotalVotes += o.Votes
+	}
+	for _, poll := range result {
+		votes := make([]int64, len(poll.Options))
+		for i, o := range poll.Options {
+			votes[i] = o.Votes
+		}
+		for i, percentage := range polls.Percentages(votes) {
+			poll.Options[i].Percentage = percentage
+		}
+	}
+	if viewerID != uuid.Nil {
+		votes, err := cfg.dbQueries.ListPollVotesByUser(ctx, database.ListPollVotesByUserParams{UserID: viewerID, ChirpIds: ids})
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range votes {
+			option := int(v.Position)
+			result[v.ChirpID].Voted = true
+			result[v.ChirpID].VotedOption = &option
+		}
+	}
+	return result, nil
+}
+
+func (cfg *apiConfig) handlerPollVote(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.requireUser(w, req)
+	if !ok {
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	voteReq := PollVoteRequest{}
+	decoder := json.NewDecoder(req.Body)
+	if err = decoder.Decode(&voteReq); err != nil {
+		respondWithError(w, 400, "Error decoding request body")
+		return
+	}
+	dbChirp, err := cfg.dbQueries.GetChirp(req.Context(), chirpID)
+	if err != nil || !canView(dbChirp, userID, true) {
+		respondWithError(w, 404, "Chirp doesn't exist")
+		return
+	}
+	poll, err := cfg.dbQueries.GetPoll(req.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, 404, "Chirp doesn't have a poll")
+		return
+	}
+	if !time.Now().Before(poll.ExpiresAt) {
+		respondWithError(w, 400, "Poll is closed")
+		return
+	}
+	if voteReq.Option < 0 || voteReq.Option >= polls.MaxOptions {
+		respondWithError(w, 400, "Invalid poll option")
+		return
+	}
+	blocked, err := cfg.dbQueries.IsBlockedBetween(req.Context(), database.IsBlockedBetweenParams{UserA: userID, UserB: dbChirp.UserID})
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	if blocked {
+		respondWithError(w, 403, "You can't vote on this poll")
+		return
+	}
+	voted, err := cfg.dbQueries.CreatePollVote(req.Context(), database.CreatePollVoteParams{ChirpID: chirpID, UserID: userID, Position: int32(voteReq.Option)})
+	if err != nil {
+		// The option doesn't exist on this poll.
+		respondWithError(w, 400, "Invalid poll option")
+		return
+	}
+	if voted == 0 {
+		respondWithError(w, 409, "You already voted on this poll")
+		return
+	}
+	chirps, err := cfg.renderChirps(req.Context(), userID, []database.Chirp{dbChirp})
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	respondWithJSON(w, 200, chirps[0])
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"chirpy/internal/config"
+	"context"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	maxHeaderBytes = 1 << 16
+	dbPingTimeout  = 5 * time.Second
+)
+
+// startWorker runs f in the background, counted by cfg.workers so shutdown
+// can wait for it to return.
+func (cfg *apiConfig) startWorker(f func()) {
+	cfg.workers.Add(1)
+	go func() {
+		defer cfg.workers.Done()
+		f()
+	}()
+}
+
+// newServer configures the HTTP server. WriteTimeout doesn't apply to SSE and
+// WebSocket connections, which clear their deadlines.
+func newServer(handler http.Handler, conf config.Config) *http.Server {
+	return &http.Server{
+		Addr:              conf.Addr(),
+		Handler:           handler,
+		ReadHeaderTimeout: time.Duration(conf.HTTPReadHeaderTimeoutSeconds) * time.Second,
+		ReadTimeout:       time.Duration(conf.HTTPReadTimeoutSeconds) * time.Second,
+		WriteTimeout:      time.Duration(conf.HTTPWriteTimeoutSeconds) * time.Second,
+		IdleTimeout:       time.Duration(conf.HTTPIdleTimeoutSeconds) * time.Second,
+		MaxHeaderBytes:    maxHeaderBytes,
+	}
+}
+
+// limitBody caps request bodies at maxBytes. Media uploads set their own,
+// larger limit.
+func limitBody(next http.Handler, maxBytes int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !(req.Method == "POST" && req.URL.Path == "/api/media") {
+			req.Body = http.MaxBytesReader(w, req.Body, maxBytes)
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// clearDeadlines lifts the server's read and write timeouts for a
+// long-lived streaming connection.
+func clearDeadlines(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+}
+
+// shutdown stops the server gracefully within timeout: it stops accepting
+// connections, closes streams so clients reconnect elsewhere, waits for
+// in-flight requests, then stops the background workers with stopWorkers
+// and waits for them to finish what they're doing. Whatever is still
+// running at the deadline is abandoned; leases and SKIP LOCKED claims let
+// another instance pick it up.
+func (cfg *apiConfig) shutdown(server *http.Server, stopWorkers context.CancelFunc, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	close(cfg.closing)
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Error shutting down server: %s", err)
+	}
+	stopWorkers()
+	done := make(chan struct{})
+	go func() {
+		cfg.streams.Wait()
+		cfg.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Print("Shutdown deadline passed with work still running")
+	}
+}
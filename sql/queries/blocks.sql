@@ -0,0 +1,37 @@
+-- name: CreateBlock :one
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (blocker_id, blocked_id) DO UPDATE SET created_at=blocks.created_at
+RETURNING *;
+
+-- name: DeleteBlock :exec
+DELETE FROM blocks
+WHERE blocker_id=$1 AND blocked_id=$2;
+
+-- name: ListBlocks :many
+SELECT * FROM blocks WHERE blocker_id=$1 ORDER BY created_at DESC;
+
+-- name: CreateMute :one
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (muter_id, muted_id) DO UPDATE SET created_at=mutes.created_at
+RETURNING *;
+
+-- name: DeleteMute :exec
+DELETE FROM mutes
+WHERE muter_id=$1 AND muted_id=$2;
+
+-- name: ListMutes :many
+SELECT * FROM mutes WHERE muter_id=$1 ORDER BY created_at DESC;
+
+-- name: ListHiddenAuthors :many
+SELECT blocked_id AS author_id FROM blocks WHERE blocker_id=sqlc.arg(viewer_id)
+UNION
+SELECT muted_id AS author_id FROM mutes WHERE muter_id=sqlc.arg(viewer_id);
+
+-- name: IsBlockedBetween :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocker_id=sqlc.arg(user_a) AND blocked_id=sqlc.arg(user_b))
+       OR (blocker_id=sqlc.arg(user_b) AND blocked_id=sqlc.arg(user_a))
+);
@@ -0,0 +1,48 @@
+-- name: CreateBookmark :one
+INSERT INTO bookmarks (id, created_at, user_id, chirp_id, collection_id)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3)
+ON CONFLICT (user_id, chirp_id) DO UPDATE SET collection_id=EXCLUDED.collection_id
+RETURNING *;
+
+-- name: DeleteBookmark :execrows
+DELETE FROM bookmarks WHERE user_id=$1 AND chirp_id=$2;
+
+-- name: ListBookmarks :many
+SELECT bookmarks.id, bookmarks.created_at, bookmarks.collection_id, sqlc.embed(chirps)
+FROM bookmarks
+JOIN chirps ON chirps.id=bookmarks.chirp_id
+WHERE bookmarks.user_id=sqlc.arg(user_id)
+  AND (sqlc.narg(collection_id)::uuid IS NULL OR bookmarks.collection_id=sqlc.narg(collection_id)::uuid)
+  AND (sqlc.narg(before_created_at)::timestamp IS NULL
+       OR (bookmarks.created_at, bookmarks.id) < (sqlc.narg(before_created_at)::timestamp, sqlc.narg(before_id)::uuid))
+ORDER BY bookmarks.created_at DESC, bookmarks.id DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: ListBookmarkedChirps :many
+SELECT chirp_id FROM bookmarks
+WHERE user_id=$1 AND chirp_id=ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: CreateBookmarkCollection :one
+INSERT INTO bookmark_collections (id, created_at, updated_at, user_id, name)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
+RETURNING *;
+
+-- name: GetBookmarkCollection :one
+SELECT * FROM bookmark_collections WHERE id=$1 AND user_id=$2;
+
+-- name: ListBookmarkCollections :many
+SELECT bookmark_collections.*, COUNT(bookmarks.id) AS bookmark_count
+FROM bookmark_collections
+LEFT JOIN bookmarks ON bookmarks.collection_id=bookmark_collections.id
+WHERE bookmark_collections.user_id=$1
+GROUP BY bookmark_collections.id
+ORDER BY bookmark_collections.name;
+
+-- name: RenameBookmarkCollection :one
+UPDATE bookmark_collections
+SET updated_at=NOW(), name=$3
+WHERE id=$1 AND user_id=$2
+RETURNING *;
+
+-- name: DeleteBookmarkCollection :execrows
+DELETE FROM bookmark_collections WHERE id=$1 AND user_id=$2;
@@ -0,0 +1,32 @@
+-- name: CreateChirpEvent :one
+-- The lock is held until the inserting transaction ends, so events commit
+-- in ID order and a client resuming after an ID can't miss a lower one that
+-- committed later.
+INSERT INTO chirp_events (created_at, type, chirp_id, user_id, body, visibility)
+SELECT NOW(), sqlc.arg(type)::text, sqlc.arg(chirp_id)::uuid, sqlc.arg(user_id)::uuid, sqlc.arg(body)::text, sqlc.arg(visibility)::text
+FROM (SELECT pg_advisory_xact_lock(hashtext('chirp_events'))) AS serialized
+RETURNING *;
+
+-- name: ListChirpEventsAfter :many
+SELECT * FROM chirp_events
+WHERE id>$1
+ORDER BY id ASC
+LIMIT $2;
+
+-- name: GetChirpEvent :one
+SELECT * FROM chirp_events WHERE id=$1;
+
+-- name: NotifyChirpEvent :exec
+-- Only the ID is sent, since pg_notify payloads are limited to 8000 bytes;
+-- listeners load the event itself.
+SELECT pg_notify('chirp_events', sqlc.arg(id)::bigint::text);
+
+-- name: DeleteOldChirpEvents :execrows
+-- Deletes up to batch_size events created before cutoff.
+DELETE FROM chirp_events
+WHERE id IN (
+    SELECT id FROM chirp_events
+    WHERE created_at < sqlc.arg(cutoff)::timestamp
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+);
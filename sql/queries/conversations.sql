@@ -0,0 +1,73 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, is_group)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1)
+RETURNING *;
+
+-- name: AddConversationParticipant :exec
+INSERT INTO conversation_participants (conversation_id, user_id, joined_at)
+VALUES ($1, $2, NOW());
+
+-- name: GetDirectConversation :one
+SELECT c.* FROM conversations c
+JOIN conversation_participants p1 ON p1.conversation_id=c.id AND p1.user_id=sqlc.arg(user_a)
+JOIN conversation_participants p2 ON p2.conversation_id=c.id AND p2.user_id=sqlc.arg(user_b)
+WHERE NOT c.is_group
+LIMIT 1;
+
+-- name: GetConversationForParticipant :one
+SELECT c.id, c.created_at, c.updated_at, c.is_group, p.last_read_at
+FROM conversations c
+JOIN conversation_participants p ON p.conversation_id=c.id
+WHERE c.id=$1 AND p.user_id=$2;
+
+-- name: ListConversationsForUser :many
+SELECT c.id, c.created_at, c.updated_at, c.is_group, p.last_read_at,
+    (SELECT COUNT(*) FROM messages m
+     WHERE m.conversation_id=c.id
+       AND m.sender_id<>p.user_id
+       AND (p.last_read_at IS NULL OR m.created_at>p.last_read_at)
+       AND NOT EXISTS (SELECT 1 FROM blocks b WHERE b.blocker_id=p.user_id AND b.blocked_id=m.sender_id)
+    ) AS unread_count
+FROM conversations c
+JOIN conversation_participants p ON p.conversation_id=c.id
+WHERE p.user_id=$1
+ORDER BY c.updated_at DESC;
+
+-- name: ListConversationParticipants :many
+SELECT user_id FROM conversation_participants
+WHERE conversation_id=$1
+ORDER BY joined_at ASC, user_id ASC;
+
+-- name: TouchConversation :exec
+UPDATE conversations
+SET updated_at=NOW()
+WHERE id=$1;
+
+-- name: MarkConversationRead :exec
+UPDATE conversation_participants
+SET last_read_at=GREATEST(last_read_at, sqlc.arg(read_at)::timestamp)
+WHERE conversation_id=$1 AND user_id=$2;
+
+-- name: CountUnreadMessages :one
+SELECT COUNT(*) FROM messages m
+JOIN conversation_participants p ON p.conversation_id=m.conversation_id
+WHERE p.user_id=$1
+  AND m.sender_id<>p.user_id
+  AND (p.last_read_at IS NULL OR m.created_at>p.last_read_at)
+  AND NOT EXISTS (SELECT 1 FROM blocks b WHERE b.blocker_id=p.user_id AND b.blocked_id=m.sender_id);
+
+-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3)
+RETURNING *;
+
+-- name: GetMessage :one
+SELECT * FROM messages WHERE id=$1 AND conversation_id=$2;
+
+-- name: ListMessages :many
+SELECT * FROM messages
+WHERE conversation_id=sqlc.arg(conversation_id)
+  AND (sqlc.narg(before_created_at)::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg(before_created_at)::timestamp, sqlc.narg(before_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(max_results);
@@ -0,0 +1,45 @@
+-- name: CreateDraft :one
+INSERT INTO drafts (id, created_at, updated_at, user_id, body, media_ids, poll_options, poll_expires_at, publish_at, visibility)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetDraft :one
+SELECT * FROM drafts WHERE id=$1 AND user_id=$2;
+
+-- name: ListDrafts :many
+SELECT * FROM drafts
+WHERE user_id=$1
+ORDER BY updated_at DESC;
+
+-- name: UpdateDraft :one
+UPDATE drafts
+SET updated_at=NOW(), body=$3, media_ids=$4, poll_options=$5, poll_expires_at=$6, publish_at=$7, visibility=$8, publish_error=NULL, publish_attempts=0, next_attempt_at=NULL
+WHERE id=$1 AND user_id=$2
+RETURNING *;
+
+-- name: DeleteDraft :execrows
+DELETE FROM drafts WHERE id=$1 AND user_id=$2;
+
+-- name: LockDraft :one
+SELECT * FROM drafts WHERE id=$1 AND user_id=$2
+FOR UPDATE;
+
+-- name: ClaimDueDraft :one
+-- Locked rows belong to another instance that's publishing them right now.
+SELECT * FROM drafts
+WHERE publish_at <= sqlc.arg(now)::timestamp
+  AND (next_attempt_at IS NULL OR next_attempt_at <= sqlc.arg(now)::timestamp)
+ORDER BY publish_at
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: FailScheduledDraft :exec
+UPDATE drafts
+SET updated_at=NOW(), publish_at=NULL, publish_error=$2, publish_attempts=0, next_attempt_at=NULL
+WHERE id=$1;
+
+-- name: RetryScheduledDraft :one
+UPDATE drafts
+SET publish_attempts=publish_attempts+1, next_attempt_at=sqlc.arg(next_attempt_at)::timestamp, publish_error=$2
+WHERE id=$1
+RETURNING publish_attempts;
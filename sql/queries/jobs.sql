@@ -0,0 +1,70 @@
+-- name: EnqueueJob :one
+-- Returns no rows when a job with the same kind and unique key is already
+-- waiting or running.
+INSERT INTO jobs (id, created_at, updated_at, kind, payload, status, unique_key, max_attempts, run_at)
+VALUES (gen_random_uuid(), NOW(), NOW(), sqlc.arg(kind), sqlc.arg(payload), 'queued', sqlc.narg(unique_key), sqlc.arg(max_attempts), sqlc.arg(run_at))
+ON CONFLICT (kind, unique_key) WHERE unique_key IS NOT NULL AND status IN ('queued', 'running')
+DO NOTHING
+RETURNING *;
+
+-- name: ClaimJob :one
+-- Takes the next due job, or one whose worker's lease ran out, and leases it
+-- until locked_until. Locked rows are being claimed by another worker.
+UPDATE jobs
+SET updated_at=NOW(), status='running', attempts=attempts+1, locked_until=sqlc.arg(locked_until)::timestamp
+WHERE id = (
+    SELECT id FROM jobs
+    WHERE (status='queued' AND run_at <= sqlc.arg(now)::timestamp)
+       OR (status='running' AND locked_until <= sqlc.arg(now)::timestamp)
+    ORDER BY run_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteJob :execrows
+-- The finishing updates only apply to the attempt that claimed the job, so
+-- a worker that outlived its lease can't overwrite the state of the worker
+-- that reclaimed the job.
+UPDATE jobs
+SET updated_at=NOW(), status='succeeded', locked_until=NULL, last_error=NULL, finished_at=NOW()
+WHERE id=sqlc.arg(id) AND attempts=sqlc.arg(attempts) AND status='running';
+
+-- name: RetryJobLater :execrows
+UPDATE jobs
+SET updated_at=NOW(), status='queued', locked_until=NULL, last_error=sqlc.arg(last_error), run_at=sqlc.arg(run_at)
+WHERE id=sqlc.arg(id) AND attempts=sqlc.arg(attempts) AND status='running';
+
+-- name: KillJob :execrows
+UPDATE jobs
+SET updated_at=NOW(), status='dead', locked_until=NULL, last_error=sqlc.arg(last_error), finished_at=NOW()
+WHERE id=sqlc.arg(id) AND attempts=sqlc.arg(attempts) AND status='running';
+
+-- name: GetJob :one
+SELECT * FROM jobs WHERE id=$1;
+
+-- name: ListJobs :many
+SELECT * FROM jobs
+WHERE (sqlc.narg(status)::text IS NULL OR status=sqlc.narg(status)::text)
+  AND (sqlc.narg(kind)::text IS NULL OR kind=sqlc.narg(kind)::text)
+  AND (sqlc.narg(before_created_at)::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg(before_created_at)::timestamp, sqlc.narg(before_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: RequeueDeadJob :one
+-- Gives a dead job a fresh set of attempts.
+UPDATE jobs
+SET updated_at=NOW(), status='queued', attempts=0, run_at=NOW(), finished_at=NULL
+WHERE id=$1 AND status='dead'
+RETURNING *;
+
+-- name: DeleteFinishedJobs :execrows
+-- Deletes up to batch_size jobs that succeeded or died before cutoff.
+DELETE FROM jobs
+WHERE id IN (
+    SELECT id FROM jobs
+    WHERE status IN ('succeeded', 'dead') AND finished_at < sqlc.arg(cutoff)::timestamp
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+);
@@ -0,0 +1,65 @@
+-- name: CreateMedia :one
+INSERT INTO media (id, created_at, updated_at, user_id, storage_key, mime_type, size_bytes, width, height, alt_text)
+VALUES ($1, NOW(), NOW(), $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetMedia :one
+SELECT * FROM media WHERE id=$1;
+
+-- name: UpdateMediaAltText :one
+UPDATE media
+SET updated_at=NOW(), alt_text=$3
+WHERE id=$1 AND user_id=$2
+RETURNING *;
+
+-- name: AttachMediaToChirp :exec
+INSERT INTO chirp_media (chirp_id, media_id, position)
+VALUES ($1, $2, $3);
+
+-- name: IsMediaAttached :one
+SELECT EXISTS (SELECT 1 FROM chirp_media WHERE media_id=$1);
+
+-- name: GetChirpForMedia :one
+SELECT chirps.* FROM chirps
+JOIN chirp_media ON chirp_media.chirp_id=chirps.id
+WHERE chirp_media.media_id=$1;
+
+-- name: ListMediaForChirps :many
+SELECT chirp_media.chirp_id, sqlc.embed(media)
+FROM chirp_media
+JOIN media ON media.id=chirp_media.media_id
+WHERE chirp_media.chirp_id=ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_media.chirp_id, chirp_media.position;
+
+-- name: ClaimMedia :one
+-- Media is processed by one job at a time, so an upload left processing is
+-- one whose previous attempt failed or outlived its job's lease.
+UPDATE media
+SET updated_at=NOW(), status='processing'
+WHERE id=$1 AND status IN ('pending', 'processing')
+RETURNING *;
+
+-- name: MarkMediaReady :one
+UPDATE media
+SET updated_at=NOW(), status='ready', storage_key=$2, mime_type=$3, size_bytes=$4, width=$5, height=$6, blurhash=$7
+WHERE id=$1
+RETURNING *;
+
+-- name: MarkMediaFailed :exec
+UPDATE media
+SET updated_at=NOW(), status='failed'
+WHERE id=$1;
+
+-- name: CreateMediaVariant :exec
+INSERT INTO media_variants (media_id, name, storage_key, mime_type, size_bytes, width, height)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (media_id, name) DO UPDATE
+SET storage_key=EXCLUDED.storage_key, mime_type=EXCLUDED.mime_type, size_bytes=EXCLUDED.size_bytes, width=EXCLUDED.width, height=EXCLUDED.height;
+
+-- name: GetMediaVariant :one
+SELECT * FROM media_variants WHERE media_id=$1 AND name=$2;
+
+-- name: ListMediaVariants :many
+SELECT * FROM media_variants
+WHERE media_id=ANY(sqlc.arg(media_ids)::uuid[])
+ORDER BY media_id, width;
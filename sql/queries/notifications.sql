@@ -0,0 +1,29 @@
+-- name: CreateNotification :one
+INSERT INTO notifications (id, created_at, user_id, type, actor_id, chirp_id)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4)
+RETURNING *;
+
+-- name: ListNotifications :many
+SELECT * FROM notifications
+WHERE user_id=sqlc.arg(user_id)
+  AND (sqlc.narg(before_created_at)::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg(before_created_at)::timestamp, sqlc.narg(before_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id=$1 AND read_at IS NULL;
+
+-- name: MarkNotificationsRead :exec
+UPDATE notifications
+SET read_at=NOW()
+WHERE user_id=$1 AND id=ANY(sqlc.arg(ids)::uuid[]) AND read_at IS NULL;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications
+SET read_at=NOW()
+WHERE user_id=$1 AND read_at IS NULL;
+
+-- name: NotifyNotificationEvent :exec
+SELECT pg_notify('notification_events', sqlc.arg(payload)::text);
@@ -0,0 +1,46 @@
+-- name: CreateOutboxMessage :exec
+INSERT INTO outbox (id, created_at, aggregate_type, aggregate_id, event_type, payload, next_attempt_at)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4, NOW());
+
+-- name: ClaimOutboxBatch :many
+-- Leases a batch of due events until locked_until, so they can be published
+-- outside a transaction and a relay that dies mid-batch only delays them.
+-- Locked rows are being claimed by another relay.
+WITH claimed AS (
+    UPDATE outbox
+    SET locked_until=sqlc.arg(locked_until)::timestamp
+    WHERE id IN (
+        SELECT id FROM outbox
+        WHERE published_at IS NULL AND failed_at IS NULL AND next_attempt_at <= sqlc.arg(now)::timestamp
+          AND (locked_until IS NULL OR locked_until <= sqlc.arg(now)::timestamp)
+        ORDER BY created_at, id
+        LIMIT sqlc.arg(max_results)
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING *
+)
+SELECT * FROM claimed
+ORDER BY created_at, id;
+
+-- name: MarkOutboxPublished :exec
+UPDATE outbox
+SET published_at=NOW(), attempts=attempts+1, last_error=NULL, locked_until=NULL
+WHERE id=$1;
+
+-- name: RecordOutboxFailure :exec
+-- Gives up on the event once it has failed max_attempts times.
+UPDATE outbox
+SET attempts=attempts+1, last_error=sqlc.arg(last_error), next_attempt_at=sqlc.arg(next_attempt_at), locked_until=NULL,
+    failed_at=CASE WHEN attempts+1 >= sqlc.arg(max_attempts)::int THEN NOW() END
+WHERE id=sqlc.arg(id);
+
+-- name: DeleteFinishedOutbox :execrows
+-- Deletes up to batch_size events that were published or given up on before
+-- cutoff.
+DELETE FROM outbox
+WHERE id IN (
+    SELECT id FROM outbox
+    WHERE published_at < sqlc.arg(cutoff)::timestamp OR failed_at < sqlc.arg(cutoff)::timestamp
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+);
@@ -0,0 +1,31 @@
+-- name: PinChirp :execrows
+INSERT INTO pinned_chirps (user_id, chirp_id, position, created_at)
+VALUES (
+    sqlc.arg(user_id),
+    sqlc.arg(chirp_id),
+    (SELECT COALESCE(MAX(position) + 1, 0) FROM pinned_chirps WHERE user_id=sqlc.arg(user_id)),
+    NOW()
+)
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: UnpinChirp :execrows
+DELETE FROM pinned_chirps WHERE user_id=$1 AND chirp_id=$2;
+
+-- name: CountPinnedChirps :one
+SELECT COUNT(*) FROM pinned_chirps WHERE user_id=$1;
+
+-- name: ListPinnedChirps :many
+SELECT sqlc.embed(chirps)
+FROM pinned_chirps
+JOIN chirps ON chirps.id=pinned_chirps.chirp_id
+WHERE pinned_chirps.user_id=$1
+ORDER BY pinned_chirps.position;
+
+-- name: ListPinnedAmong :many
+SELECT chirp_id FROM pinned_chirps
+WHERE chirp_id=ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: SetPinPosition :execrows
+UPDATE pinned_chirps
+SET position=$3
+WHERE user_id=$1 AND chirp_id=$2;
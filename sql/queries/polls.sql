@@ -0,0 +1,31 @@
+-- name: CreatePoll :exec
+INSERT INTO polls (chirp_id, expires_at)
+VALUES ($1, $2);
+
+-- name: CreatePollOption :exec
+INSERT INTO poll_options (chirp_id, position, text)
+VALUES ($1, $2, $3);
+
+-- name: GetPoll :one
+SELECT * FROM polls WHERE chirp_id=$1;
+
+-- name: ListPollsForChirps :many
+SELECT * FROM polls
+WHERE chirp_id=ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: ListPollResults :many
+SELECT poll_options.chirp_id, poll_options.position, poll_options.text, COUNT(poll_votes.user_id) AS votes
+FROM poll_options
+LEFT JOIN poll_votes ON poll_votes.chirp_id=poll_options.chirp_id AND poll_votes.position=poll_options.position
+WHERE poll_options.chirp_id=ANY(sqlc.arg(chirp_ids)::uuid[])
+GROUP BY poll_options.chirp_id, poll_options.position, poll_options.text
+ORDER BY poll_options.chirp_id, poll_options.position;
+
+-- name: ListPollVotesByUser :many
+SELECT chirp_id, position FROM poll_votes
+WHERE user_id=$1 AND chirp_id=ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: CreatePollVote :execrows
+INSERT INTO poll_votes (chirp_id, user_id, position, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (chirp_id, user_id) DO NOTHING;
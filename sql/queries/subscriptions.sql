@@ -0,0 +1,17 @@
+-- name: GetSubscription :one
+SELECT * FROM subscriptions WHERE user_id=$1;
+
+-- name: UpsertSubscription :one
+INSERT INTO subscriptions (id, created_at, updated_at, user_id, plan, status, current_period_start, current_period_end)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5)
+ON CONFLICT (user_id) DO UPDATE
+SET updated_at=NOW(), plan=EXCLUDED.plan, status=EXCLUDED.status,
+    current_period_start=EXCLUDED.current_period_start, current_period_end=EXCLUDED.current_period_end
+RETURNING *;
+
+-- name: ExpireSubscriptions :many
+-- Returns the users whose subscription lapsed.
+UPDATE subscriptions
+SET updated_at=NOW(), status='expired'
+WHERE status <> 'expired' AND current_period_end <= sqlc.arg(now)
+RETURNING user_id, plan, current_period_end;
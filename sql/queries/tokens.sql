@@ -8,4 +8,15 @@ SELECT user_id, expires_at, revoked_at FROM refresh_tokens WHERE token=$1;
 -- name: RevokeToken :exec
 UPDATE refresh_tokens 
 SET updated_at=NOW(), revoked_at=NOW()
-WHERE token=$1;
\ No newline at end of file
+WHERE token=$1;
+
+-- name: DeleteStaleRefreshTokens :execrows
+-- Deletes up to batch_size tokens that expired or were revoked before
+-- cutoff.
+DELETE FROM refresh_tokens
+WHERE token IN (
+    SELECT token FROM refresh_tokens
+    WHERE expires_at < sqlc.arg(cutoff)::timestamp OR revoked_at < sqlc.arg(cutoff)::timestamp
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+);
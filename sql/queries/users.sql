@@ -9,13 +9,26 @@ DELETE FROM users;
 -- name: GetUser :one
 SELECT * FROM users WHERE email=$1;
 
+-- name: GetUserByID :one
+SELECT * FROM users WHERE id=$1;
+
 -- name: ChangeEmailAndPassword :one
 UPDATE users
 SET updated_at=NOW(), email=$2, hashed_password=$3
 WHERE id=$1
 RETURNING *;
 
--- name: UpgradeUserToRed :exec
+-- name: SyncUserChirpyRed :exec
+-- is_chirpy_red caches whether the user's subscription entitles them to
+-- Chirpy Red at now.
 UPDATE users
-SET updated_at=NOW(), is_chirpy_red=true
-WHERE id=$1;
\ No newline at end of file
+SET updated_at=NOW(), is_chirpy_red=EXISTS (
+    SELECT 1 FROM subscriptions
+    WHERE subscriptions.user_id=users.id
+      AND status IN ('active', 'past_due')
+      AND current_period_end > sqlc.arg(now)
+)
+WHERE users.id=sqlc.arg(user_id);
+
+-- name: LockUser :exec
+SELECT id FROM users WHERE id=$1 FOR UPDATE;
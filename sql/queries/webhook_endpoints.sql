@@ -0,0 +1,77 @@
+-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints (id, created_at, updated_at, user_id, url, secret, events)
+VALUES (gen_random_uuid(), NOW(), NOW(), sqlc.narg(user_id), sqlc.arg(url), sqlc.arg(secret), sqlc.arg(events)::text[])
+RETURNING *;
+
+-- name: ListWebhookEndpoints :many
+SELECT * FROM webhook_endpoints
+WHERE user_id IS NOT DISTINCT FROM sqlc.narg(user_id)
+ORDER BY created_at, id;
+
+-- name: GetWebhookEndpoint :one
+SELECT * FROM webhook_endpoints
+WHERE id=sqlc.arg(id) AND user_id IS NOT DISTINCT FROM sqlc.narg(user_id);
+
+-- name: GetWebhookEndpointByID :one
+SELECT * FROM webhook_endpoints WHERE id=$1;
+
+-- name: DeleteWebhookEndpoint :execrows
+DELETE FROM webhook_endpoints
+WHERE id=sqlc.arg(id) AND user_id IS NOT DISTINCT FROM sqlc.narg(user_id);
+
+-- name: EnqueueWebhookDeliveries :execrows
+-- Queues an event for every active endpoint subscribed to it: admin
+-- endpoints, and those of the user the event is about.
+INSERT INTO webhook_deliveries (id, created_at, updated_at, endpoint_id, event_id, event_type, payload, status, next_attempt_at)
+SELECT gen_random_uuid(), NOW(), NOW(), id, sqlc.arg(event_id), sqlc.arg(event_type)::text, sqlc.arg(payload), 'pending', NOW()
+FROM webhook_endpoints
+WHERE active
+  AND sqlc.arg(event_type)::text = ANY(events)
+  AND (user_id IS NULL OR user_id=sqlc.arg(user_id)::uuid);
+
+-- name: ClaimWebhookDelivery :one
+-- Leases the next due delivery until lease_until, so a worker that dies
+-- mid-attempt only delays it. Locked rows are being claimed by another
+-- worker.
+UPDATE webhook_deliveries
+SET updated_at=NOW(), attempts=attempts+1, last_attempt_at=sqlc.arg(now)::timestamp, next_attempt_at=sqlc.arg(lease_until)
+WHERE id = (
+    SELECT id FROM webhook_deliveries
+    WHERE status='pending' AND next_attempt_at <= sqlc.arg(now)::timestamp
+    ORDER BY next_attempt_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: RecordWebhookDeliveryAttempt :exec
+UPDATE webhook_deliveries
+SET updated_at=NOW(), status=$2, next_attempt_at=$3, response_status=$4, response_body=$5, error=$6
+WHERE id=$1;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE endpoint_id=sqlc.arg(endpoint_id)
+  AND (sqlc.narg(before_created_at)::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg(before_created_at)::timestamp, sqlc.narg(before_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: RedeliverWebhookDelivery :one
+-- Queues a copy of a delivery, keeping the original's log.
+INSERT INTO webhook_deliveries (id, created_at, updated_at, endpoint_id, event_id, event_type, payload, status, next_attempt_at)
+SELECT gen_random_uuid(), NOW(), NOW(), endpoint_id, event_id, event_type, payload, 'pending', NOW()
+FROM webhook_deliveries
+WHERE webhook_deliveries.id=sqlc.arg(id) AND webhook_deliveries.endpoint_id=sqlc.arg(endpoint_id)
+RETURNING *;
+
+-- name: DeleteFinishedWebhookDeliveries :execrows
+-- Deletes up to batch_size deliveries that succeeded or failed for good
+-- before cutoff.
+DELETE FROM webhook_deliveries
+WHERE id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status IN ('succeeded', 'failed') AND updated_at < sqlc.arg(cutoff)::timestamp
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+);
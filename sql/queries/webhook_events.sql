@@ -0,0 +1,45 @@
+-- name: CreateWebhookEvent :one
+-- Returns no rows when the event was already received.
+INSERT INTO webhook_events (id, created_at, updated_at, provider, event_id, event_type, payload)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4)
+ON CONFLICT (provider, event_id) DO NOTHING
+RETURNING *;
+
+-- name: GetWebhookEvent :one
+SELECT * FROM webhook_events WHERE id=$1;
+
+-- name: GetWebhookEventByEventID :one
+SELECT * FROM webhook_events WHERE provider=$1 AND event_id=$2;
+
+-- name: LockWebhookEvent :one
+SELECT * FROM webhook_events WHERE id=$1
+FOR UPDATE;
+
+-- name: ListWebhookEvents :many
+SELECT * FROM webhook_events
+WHERE (sqlc.narg(status)::text IS NULL OR status=sqlc.narg(status)::text)
+  AND (sqlc.narg(before_created_at)::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg(before_created_at)::timestamp, sqlc.narg(before_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: MarkWebhookEventProcessed :exec
+UPDATE webhook_events
+SET updated_at=NOW(), status=$2, error=NULL, attempts=attempts+1, processed_at=NOW()
+WHERE id=$1;
+
+-- name: MarkWebhookEventFailed :exec
+UPDATE webhook_events
+SET updated_at=NOW(), status='failed', error=$2, attempts=attempts+1
+WHERE id=$1;
+
+-- name: DeleteFinishedWebhookEvents :execrows
+-- Deletes up to batch_size events processed or ignored before cutoff. Failed
+-- events are kept for admins to replay.
+DELETE FROM webhook_events
+WHERE id IN (
+    SELECT id FROM webhook_events
+    WHERE status IN ('processed', 'ignored') AND updated_at < sqlc.arg(cutoff)::timestamp
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+);
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE blocks (
+    blocker_id UUID REFERENCES users ON DELETE CASCADE NOT NULL,
+    blocked_id UUID REFERENCES users ON DELETE CASCADE NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (blocker_id, blocked_id)
+);
+
+CREATE TABLE mutes (
+    muter_id UUID REFERENCES users ON DELETE CASCADE NOT NULL,
+    muted_id UUID REFERENCES users ON DELETE CASCADE NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (muter_id, muted_id)
+);
+
+-- +goose Down
+DROP TABLE mutes;
+DROP TABLE blocks;
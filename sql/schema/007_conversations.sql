@@ -0,0 +1,30 @@
+-- +goose Up
+CREATE TABLE conversations (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    is_group BOOL NOT NULL
+);
+
+CREATE TABLE conversation_participants (
+    conversation_id UUID REFERENCES conversations ON DELETE CASCADE NOT NULL,
+    user_id UUID REFERENCES users ON DELETE CASCADE NOT NULL,
+    joined_at TIMESTAMP NOT NULL,
+    last_read_at TIMESTAMP,
+    PRIMARY KEY (conversation_id, user_id)
+);
+
+CREATE TABLE messages (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    conversation_id UUID REFERENCES conversations ON DELETE CASCADE NOT NULL,
+    sender_id UUID REFERENCES users ON DELETE CASCADE NOT NULL,
+    body TEXT NOT NULL
+);
+
+CREATE INDEX messages_conversation_id_created_at_idx ON messages (conversation_id, created_at, id);
+
+-- +goose Down
+DROP TABLE messages;
+DROP TABLE conversation_participants;
+DROP TABLE conversations;
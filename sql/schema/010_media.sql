@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE media (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID REFERENCES users ON DELETE CASCADE NOT NULL,
+    storage_key TEXT NOT NULL,
+    mime_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    alt_text TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE chirp_media (
+    chirp_id UUID REFERENCES chirps ON DELETE CASCADE NOT NULL,
+    media_id UUID REFERENCES media ON DELETE CASCADE NOT NULL UNIQUE,
+    position INT NOT NULL,
+    PRIMARY KEY (chirp_id, media_id)
+);
+
+-- +goose Down
+DROP TABLE chirp_media;
+DROP TABLE media;
@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE media
+ADD COLUMN status TEXT NOT NULL DEFAULT 'ready',
+ADD COLUMN blurhash TEXT;
+ALTER TABLE media ALTER COLUMN status SET DEFAULT 'pending';
+
+CREATE TABLE media_variants (
+    media_id UUID REFERENCES media ON DELETE CASCADE NOT NULL,
+    name TEXT NOT NULL,
+    storage_key TEXT NOT NULL,
+    mime_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    PRIMARY KEY (media_id, name)
+);
+
+-- +goose Down
+DROP TABLE media_variants;
+ALTER TABLE media
+DROP COLUMN blurhash,
+DROP COLUMN status;
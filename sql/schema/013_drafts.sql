@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE drafts (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID REFERENCES users ON DELETE CASCADE NOT NULL,
+    body TEXT NOT NULL,
+    media_ids UUID[] NOT NULL DEFAULT '{}',
+    poll_options TEXT[] NOT NULL DEFAULT '{}',
+    poll_expires_at TIMESTAMP,
+    publish_at TIMESTAMP,
+    publish_error TEXT
+);
+
+CREATE INDEX drafts_user_id_idx ON drafts (user_id, updated_at);
+CREATE INDEX drafts_publish_at_idx ON drafts (publish_at) WHERE publish_at IS NOT NULL;
+
+-- +goose Down
+DROP TABLE drafts;
@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE pinned_chirps (
+    user_id UUID REFERENCES users ON DELETE CASCADE NOT NULL,
+    chirp_id UUID REFERENCES chirps ON DELETE CASCADE NOT NULL UNIQUE,
+    position INT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, chirp_id)
+);
+
+-- +goose Down
+DROP TABLE pinned_chirps;
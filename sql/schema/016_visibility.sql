@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public'
+    CHECK (visibility IN ('public', 'followers', 'unlisted'));
+
+ALTER TABLE chirp_events
+ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public';
+
+ALTER TABLE drafts
+ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public';
+
+-- +goose Down
+ALTER TABLE drafts DROP COLUMN visibility;
+ALTER TABLE chirp_events DROP COLUMN visibility;
+ALTER TABLE chirps DROP COLUMN visibility;
@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE subscriptions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID REFERENCES users ON DELETE CASCADE NOT NULL UNIQUE,
+    plan TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('active', 'past_due', 'canceled', 'expired')),
+    current_period_start TIMESTAMP NOT NULL,
+    current_period_end TIMESTAMP NOT NULL
+);
+
+CREATE INDEX subscriptions_period_end_idx ON subscriptions (current_period_end)
+WHERE status <> 'expired';
+
+-- Existing members get one period from now.
+INSERT INTO subscriptions (id, created_at, updated_at, user_id, plan, status, current_period_start, current_period_end)
+SELECT gen_random_uuid(), NOW(), NOW(), id, 'chirpy_red', 'active', NOW(), NOW() + INTERVAL '30 days'
+FROM users WHERE is_chirpy_red;
+
+UPDATE users SET is_chirpy_red=false WHERE is_chirpy_red IS NULL;
+ALTER TABLE users ALTER COLUMN is_chirpy_red SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE users ALTER COLUMN is_chirpy_red DROP NOT NULL;
+DROP TABLE subscriptions;
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE jobs (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    kind TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('queued', 'running', 'succeeded', 'dead')),
+    unique_key TEXT,
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL,
+    run_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP,
+    last_error TEXT,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX jobs_due_idx ON jobs (run_at) WHERE status IN ('queued', 'running');
+CREATE INDEX jobs_status_idx ON jobs (status, created_at DESC);
+-- Only one job per key can be waiting or running at a time.
+CREATE UNIQUE INDEX jobs_unique_key_idx ON jobs (kind, unique_key)
+WHERE unique_key IS NOT NULL AND status IN ('queued', 'running');
+
+-- +goose Down
+DROP TABLE jobs;
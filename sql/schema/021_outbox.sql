@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE outbox (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    aggregate_type TEXT NOT NULL,
+    aggregate_id UUID NOT NULL,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT,
+    published_at TIMESTAMP
+);
+
+CREATE INDEX outbox_unpublished_idx ON outbox (next_attempt_at, created_at)
+WHERE published_at IS NULL;
+CREATE INDEX outbox_published_at_idx ON outbox (published_at)
+WHERE published_at IS NOT NULL;
+
+-- +goose Down
+DROP TABLE outbox;
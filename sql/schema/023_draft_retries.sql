@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE drafts
+ADD COLUMN publish_attempts INT NOT NULL DEFAULT 0,
+ADD COLUMN next_attempt_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE drafts
+DROP COLUMN next_attempt_at,
+DROP COLUMN publish_attempts;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE INDEX jobs_finished_at_idx ON jobs (finished_at)
+WHERE status IN ('succeeded', 'dead');
+CREATE INDEX webhook_deliveries_finished_idx ON webhook_deliveries (updated_at)
+WHERE status IN ('succeeded', 'failed');
+CREATE INDEX webhook_events_finished_idx ON webhook_events (updated_at)
+WHERE status IN ('processed', 'ignored');
+CREATE INDEX chirp_events_created_at_idx ON chirp_events (created_at);
+
+-- +goose Down
+DROP INDEX chirp_events_created_at_idx;
+DROP INDEX webhook_events_finished_idx;
+DROP INDEX webhook_deliveries_finished_idx;
+DROP INDEX jobs_finished_at_idx;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE outbox ADD COLUMN locked_until TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE outbox DROP COLUMN locked_until;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE outbox ADD COLUMN failed_at TIMESTAMP;
+
+CREATE INDEX outbox_failed_at_idx ON outbox (failed_at)
+WHERE failed_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX outbox_failed_at_idx;
+ALTER TABLE outbox DROP COLUMN failed_at;
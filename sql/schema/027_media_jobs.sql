@@ -0,0 +1,11 @@
+-- +goose Up
+-- Uploads are now processed by jobs; queue the ones the old media workers
+-- hadn't got to.
+INSERT INTO jobs (id, created_at, updated_at, kind, payload, status, unique_key, max_attempts, run_at)
+SELECT gen_random_uuid(), NOW(), NOW(), 'media.process', json_build_object('media_id', id), 'queued', id::text, 10, NOW()
+FROM media
+WHERE status IN ('pending', 'processing')
+ON CONFLICT DO NOTHING;
+
+-- +goose Down
+DELETE FROM jobs WHERE kind='media.process' AND status IN ('queued', 'running');
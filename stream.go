@@ -0,0 +1,162 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/stream"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	streamHeartbeat   = 15 * time.Second
+	streamBuffer      = 64
+	streamReplayBatch = 100
+)
+
+// recordChirpEvent records a chirp change in the event log, which gives it
+// the ID clients resume from, and broadcasts it to every instance. q must be
+// bound to the transaction making the change, so the event is logged if and
+// only if the change is committed. NOTIFY is delivered at commit, in commit
+// order, which the event log's lock makes ID order, so live clients never
+// see a lower ID after a higher one.
+func recordChirpEvent(ctx context.Context, q *database.Queries, eventType string, chirp database.Chirp) error {
+	e, err := q.CreateChirpEvent(ctx, database.CreateChirpEventParams{
+		Type:       eventType,
+		ChirpID:    chirp.ID,
+		UserID:     chirp.UserID,
+		Body:       chirp.Body,
+		Visibility: chirp.Visibility,
+	})
+	if err != nil {
+		return err
+	}
+	return q.NotifyChirpEvent(ctx, e.ID)
+}
+
+// chirpEventByID loads the chirp events announced on stream.ChirpChannel.
+func chirpEventByID(q *database.Queries) func(context.Context, string) (stream.Event, error) {
+	return func(ctx context.Context, payload string) (stream.Event, error) {
+		id, err := strconv.ParseInt(payload, 10, 64)
+		if err != nil {
+			return stream.Event{}, err
+		}
+		e, err := q.GetChirpEvent(ctx, id)
+		return stream.Event(e), err
+	}
+}
+
+func (cfg *apiConfig) handlerStream(w http.ResponseWriter, req *http.Request) {
+	filter := stream.Filter{Hashtag: req.URL.Query().Get("hashtag"), ViewerID: cfg.viewerID(req)}
+	if author := req.URL.Query().Get("author_id"); author != "" {
+		authorID, err := uuid.Parse(author)
+		if err != nil {
+			respondWithError(w, 400, "User not found")
+			return
+		}
+		filter.AuthorID = authorID
+	}
+	if filter.ViewerID != uuid.Nil {
+		hidden, err := cfg.dbQueries.ListHiddenAuthors(req.Context(), filter.ViewerID)
+		if err != nil {
+			respondWithError(w, 500, err.Error())
+			return
+		}
+		filter.HiddenAuthors = hidden
+	}
+	var lastID int64
+	resume := req.Header.Get("Last-Event-ID")
+	if resume != "" {
+		id, err := strconv.ParseInt(resume, 10, 64)
+		if err != nil {
+			respondWithError(w, 400, "Invalid Last-Event-ID")
+			return
+		}
+		lastID = id
+	}
+
+	// Subscribe before replaying so nothing published in between is missed;
+	// live events already covered by the replay are skipped by ID.
+	sub := cfg.chirpEvents.Subscribe(filter.Match, streamBuffer)
+	defer cfg.chirpEvents.Unsubscribe(sub)
+
+	rc := http.NewResponseController(w)
+	clearDeadlines(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(200)
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	if resume != "" {
+		for {
+			events, err := cfg.dbQueries.ListChirpEventsAfter(req.Context(), database.ListChirpEventsAfterParams{ID: lastID, Limit: streamReplayBatch})
+			if err != nil {
+				return
+			}
+			for _, e := range events {
+				lastID = e.ID
+				if !filter.Match(stream.Event(e)) {
+					continue
+				}
+				if err = writeEvent(w, stream.Event(e)); err != nil {
+					return
+				}
+			}
+			if len(events) < streamReplayBatch {
+				break
+			}
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-cfg.closing:
+			// The client reconnects, to another instance, and resumes
+			// with Last-Event-ID.
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind; the client reconnects with
+				// Last-Event-ID and catches up from the event log.
+				return
+			}
+			if e.ID <= lastID {
+				continue
+			}
+			lastID = e.ID
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w io.Writer, e stream.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
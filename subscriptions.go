@@ -0,0 +1,103 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/subscriptions"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const subscriptionExpiryInterval = time.Minute
+
+// applySubscriptionEvent updates the user's subscription for a Polka event,
+// re-derives is_chirpy_red from it and records the change in the outbox. q
+// should be bound to a transaction. It reports false when the event
+// doesn't change anything, say a payment failure for a lapsed subscription.
+func applySubscriptionEvent(ctx context.Context, q *database.Queries, webhook Webhook, now time.Time) (bool, error) {
+	if _, err := q.GetUserByID(ctx, webhook.Data.UserID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, errUserNotFound
+		}
+		return false, err
+	}
+	// Serializes events for the same user.
+	if err := q.LockUser(ctx, webhook.Data.UserID); err != nil {
+		return false, err
+	}
+	var current *subscriptions.Subscription
+	dbSub, err := q.GetSubscription(ctx, webhook.Data.UserID)
+	if err == nil {
+		current = &subscriptions.Subscription{
+			Plan:        dbSub.Plan,
+			Status:      dbSub.Status,
+			PeriodStart: dbSub.CurrentPeriodStart,
+			PeriodEnd:   dbSub.CurrentPeriodEnd,
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+	next, applied := subscriptions.Apply(current, subscriptions.Event{
+		Type:        webhook.Event,
+		Plan:        webhook.Data.Plan,
+		PeriodStart: webhook.Data.PeriodStart.UTC(),
+		PeriodEnd:   webhook.Data.PeriodEnd.UTC(),
+	}, now)
+	if !applied {
+		return false, nil
+	}
+	_, err = q.UpsertSubscription(ctx, database.UpsertSubscriptionParams{
+		UserID:             webhook.Data.UserID,
+		Plan:               next.Plan,
+		Status:             next.Status,
+		CurrentPeriodStart: next.PeriodStart,
+		CurrentPeriodEnd:   next.PeriodEnd,
+	})
+	if err != nil {
+		return false, err
+	}
+	err = q.SyncUserChirpyRed(ctx, database.SyncUserChirpyRedParams{UserID: webhook.Data.UserID, Now: now})
+	if err != nil {
+		return false, err
+	}
+	err = recordOutbox(ctx, q, "user", webhook.Data.UserID, outboxSubscriptionUpdated, SubscriptionData{
+		UserID:      webhook.Data.UserID,
+		Plan:        next.Plan,
+		Status:      next.Status,
+		PeriodEnd:   next.PeriodEnd,
+		IsChirpyRed: subscriptions.Entitled(next, now),
+	})
+	return err == nil, err
+}
+
+// expireSubscriptions expires subscriptions whose period has ended and takes
+// away the users' Chirpy Red membership.
+func (cfg *apiConfig) expireSubscriptions(ctx context.Context) error {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	now := time.Now().UTC()
+	expired, err := qtx.ExpireSubscriptions(ctx, now)
+	if err != nil {
+		return err
+	}
+	for _, sub := range expired {
+		if err = qtx.SyncUserChirpyRed(ctx, database.SyncUserChirpyRedParams{UserID: sub.UserID, Now: now}); err != nil {
+			return err
+		}
+		err = recordOutbox(ctx, qtx, "user", sub.UserID, outboxSubscriptionUpdated, SubscriptionData{
+			UserID:    sub.UserID,
+			Plan:      sub.Plan,
+			Status:    subscriptions.StatusExpired,
+			PeriodEnd: sub.CurrentPeriodEnd,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"log"
+	"time"
+)
+
+const tokenGCInterval = time.Hour
+
+// collectRefreshTokens deletes refresh tokens that expired or were revoked
+// more than tokenRetention ago, tokenGCBatch rows at a time so no single
+// statement holds locks for long. It returns how many it deleted.
+func (cfg *apiConfig) collectRefreshTokens(ctx context.Context) (int64, error) {
+	cutoff := time.Now().UTC().Add(-cfg.tokenRetention)
+	var total int64
+	defer func() {
+		cfg.metrics.RefreshTokenGCRuns.Inc()
+		cfg.metrics.RefreshTokensRemoved.Add(float64(total))
+		cfg.metrics.RefreshTokenGCLast.SetToCurrentTime()
+	}()
+	for {
+		deleted, err := cfg.dbQueries.DeleteStaleRefreshTokens(ctx, database.DeleteStaleRefreshTokensParams{
+			Cutoff:    cutoff,
+			BatchSize: int32(cfg.tokenGCBatch),
+		})
+		total += deleted
+		if err != nil {
+			return total, err
+		}
+		if deleted < int64(cfg.tokenGCBatch) {
+			return total, nil
+		}
+		if err = ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
+
+// collectRefreshTokensJob is the job form of collectRefreshTokens.
+func (cfg *apiConfig) collectRefreshTokensJob(ctx context.Context, _ struct{}) error {
+	deleted, err := cfg.collectRefreshTokens(ctx)
+	if deleted > 0 {
+		log.Printf("Removed %d stale refresh tokens", deleted)
+	}
+	return err
+}
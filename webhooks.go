@@ -0,0 +1,252 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/pagination"
+	"chirpy/internal/webhooks"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WebhookEndpointRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+type WebhookEndpoint struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	// Secret is only returned when the endpoint is created.
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	CreatedAt      time.Time       `json:"created_at"`
+	EventID        uuid.UUID       `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at"`
+	ResponseStatus *int32          `json:"response_status"`
+	ResponseBody   *string         `json:"response_body"`
+	Error          *string         `json:"error"`
+}
+
+type WebhookDeliveryPage struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+func webhookEndpointFromDB(e database.WebhookEndpoint) WebhookEndpoint {
+	return WebhookEndpoint{ID: e.ID, CreatedAt: e.CreatedAt, UpdatedAt: e.UpdatedAt, URL: e.Url, Events: e.Events, Active: e.Active}
+}
+
+func webhookDeliveryFromDB(d database.WebhookDelivery) WebhookDelivery {
+	delivery := WebhookDelivery{
+		ID:            d.ID,
+		CreatedAt:     d.CreatedAt,
+		EventID:       d.EventID,
+		EventType:     d.EventType,
+		Payload:       d.Payload,
+		Status:        d.Status,
+		Attempts:      d.Attempts,
+		LastAttemptAt: nullTimePtr(d.LastAttemptAt),
+	}
+	if d.Status == deliveryPending {
+		delivery.NextAttemptAt = &d.NextAttemptAt
+	}
+	if d.ResponseStatus.Valid {
+		delivery.ResponseStatus = &d.ResponseStatus.Int32
+	}
+	if d.ResponseBody.Valid {
+		delivery.ResponseBody = &d.ResponseBody.String
+	}
+	if d.Error.Valid {
+		delivery.Error = &d.Error.String
+	}
+	return delivery
+}
+
+// webhookOwnerHandler handles a request for the webhook endpoints of owner:
+// a user, or admins when owner is null.
+type webhookOwnerHandler func(w http.ResponseWriter, req *http.Request, owner uuid.NullUUID)
+
+// userWebhooks serves h for the authenticated user's endpoints.
+func (cfg *apiConfig) userWebhooks(h webhookOwnerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		userID, ok := cfg.requireUser(w, req)
+		if !ok {
+			return
+		}
+		h(w, req, uuid.NullUUID{UUID: userID, Valid: true})
+	}
+}
+
+// adminWebhooks serves h for admin endpoints, which receive every event.
+func (cfg *apiConfig) adminWebhooks(h webhookOwnerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if !cfg.requireAdmin(w, req) {
+			return
+		}
+		h(w, req, uuid.NullUUID{})
+	}
+}
+
+// ownedWebhookEndpoint loads the endpoint in the request path if it belongs
+// to owner.
+func (cfg *apiConfig) ownedWebhookEndpoint(w http.ResponseWriter, req *http.Request, owner uuid.NullUUID) (database.WebhookEndpoint, bool) {
+	endpointID, err := uuid.Parse(req.PathValue("endpointID"))
+	if err != nil {
+		respondWithError(w, 404, "Webhook endpoint not found")
+		return database.WebhookEndpoint{}, false
+	}
+	endpoint, err := cfg.dbQueries.GetWebhookEndpoint(req.Context(), database.GetWebhookEndpointParams{ID: endpointID, UserID: owner})
+	if err != nil {
+		respondWithError(w, 404, "Webhook endpoint not found")
+		return database.WebhookEndpoint{}, false
+	}
+	return endpoint, true
+}
+
+func (cfg *apiConfig) handlerWebhookEndpointCreate(w http.ResponseWriter, req *http.Request, owner uuid.NullUUID) {
+	endpointReq := WebhookEndpointRequest{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&endpointReq); err != nil {
+		respondWithError(w, 400, "Error decoding request body")
+		return
+	}
+	if err := webhooks.ValidateURL(endpointReq.URL); err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	if len(endpointReq.Events) == 0 {
+		respondWithError(w, 400, "Subscribe to at least one event")
+		return
+	}
+	for _, event := range endpointReq.Events {
+		if !webhooks.ValidEvent(event) {
+			respondWithError(w, 400, "Unknown event: "+event)
+			return
+		}
+	}
+	events := slices.Clone(endpointReq.Events)
+	slices.Sort(events)
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	endpoint, err := cfg.dbQueries.CreateWebhookEndpoint(req.Context(), database.CreateWebhookEndpointParams{
+		UserID: owner,
+		Url:    endpointReq.URL,
+		Secret: secret,
+		Events: slices.Compact(events),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Error creating webhook endpoint")
+		return
+	}
+	response := webhookEndpointFromDB(endpoint)
+	response.Secret = endpoint.Secret
+	respondWithJSON(w, 201, response)
+}
+
+func (cfg *apiConfig) handlerWebhookEndpointsList(w http.ResponseWriter, req *http.Request, owner uuid.NullUUID) {
+	rows, err := cfg.dbQueries.ListWebhookEndpoints(req.Context(), owner)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	endpoints := make([]WebhookEndpoint, len(rows))
+	for i, row := range rows {
+		endpoints[i] = webhookEndpointFromDB(row)
+	}
+	respondWithJSON(w, 200, endpoints)
+}
+
+func (cfg *apiConfig) handlerWebhookEndpointDelete(w http.ResponseWriter, req *http.Request, owner uuid.NullUUID) {
+	endpointID, err := uuid.Parse(req.PathValue("endpointID"))
+	if err != nil {
+		respondWithError(w, 404, "Webhook endpoint not found")
+		return
+	}
+	deleted, err := cfg.dbQueries.DeleteWebhookEndpoint(req.Context(), database.DeleteWebhookEndpointParams{ID: endpointID, UserID: owner})
+	if err != nil {
+		respondWithError(w, 500, "Error deleting webhook endpoint")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "Webhook endpoint not found")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
+
+// handlerWebhookDeliveriesList is an endpoint's delivery log, newest first.
+func (cfg *apiConfig) handlerWebhookDeliveriesList(w http.ResponseWriter, req *http.Request, owner uuid.NullUUID) {
+	endpoint, ok := cfg.ownedWebhookEndpoint(w, req, owner)
+	if !ok {
+		return
+	}
+	limit, err := pagination.Limit(req.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	params := database.ListWebhookDeliveriesParams{EndpointID: endpoint.ID, MaxResults: int32(limit)}
+	if c := req.URL.Query().Get("cursor"); c != "" {
+		cursor, err := pagination.Decode(c)
+		if err != nil {
+			respondWithError(w, 400, err.Error())
+			return
+		}
+		params.BeforeCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	rows, err := cfg.dbQueries.ListWebhookDeliveries(req.Context(), params)
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	page := WebhookDeliveryPage{Deliveries: make([]WebhookDelivery, len(rows))}
+	for i, row := range rows {
+		page.Deliveries[i] = webhookDeliveryFromDB(row)
+	}
+	if len(rows) == limit {
+		last := rows[len(rows)-1]
+		page.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	respondWithJSON(w, 200, page)
+}
+
+// handlerWebhookRedeliver queues a delivery to be sent again as a new
+// delivery of the same event.
+func (cfg *apiConfig) handlerWebhookRedeliver(w http.ResponseWriter, req *http.Request, owner uuid.NullUUID) {
+	endpoint, ok := cfg.ownedWebhookEndpoint(w, req, owner)
+	if !ok {
+		return
+	}
+	deliveryID, err := uuid.Parse(req.PathValue("deliveryID"))
+	if err != nil {
+		respondWithError(w, 404, "Webhook delivery not found")
+		return
+	}
+	d, err := cfg.dbQueries.RedeliverWebhookDelivery(req.Context(), database.RedeliverWebhookDeliveryParams{ID: deliveryID, EndpointID: endpoint.ID})
+	if err != nil {
+		respondWithError(w, 404, "Webhook delivery not found")
+		return
+	}
+	respondWithJSON(w, 202, webhookDeliveryFromDB(d))
+}
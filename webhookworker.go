@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/webhooks"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	webhookPollInterval    = 5 * time.Second
+	webhookTimeout         = 10 * time.Second
+	webhookLease           = time.Minute
+	maxWebhookResponseBody = 1 << 10
+)
+
+// Delivery statuses.
+const (
+	deliveryPending   = "pending"
+	deliverySucceeded = "succeeded"
+	deliveryFailed    = "failed"
+)
+
+var webhookClient = webhooks.NewClient(webhookTimeout)
+
+// ChirpData is the data of chirp webhooks and outbox events.
+type ChirpData struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UserID     uuid.UUID `json:"user_id"`
+	Body       string    `json:"body"`
+	Visibility string    `json:"visibility"`
+}
+
+func chirpData(c database.Chirp) ChirpData {
+	return ChirpData{ID: c.ID, CreatedAt: c.CreatedAt, UserID: c.UserID, Body: c.Body, Visibility: c.Visibility}
+}
+
+// enqueueWebhook queues an event about userID for the endpoints subscribed
+// to it. q should be bound to the transaction making the change, so the
+// event is queued if and only if the change is committed.
+func enqueueWebhook(ctx context.Context, q *database.Queries, eventType string, userID uuid.UUID, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	envelope := webhooks.Envelope{ID: uuid.New(), Type: eventType, CreatedAt: time.Now().UTC(), Data: raw}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	_, err = q.EnqueueWebhookDeliveries(ctx, database.EnqueueWebhookDeliveriesParams{
+		EventID:   envelope.ID,
+		EventType: eventType,
+		Payload:   payload,
+		UserID:    userID,
+	})
+	return err
+}
+
+// runWebhookDeliveries sends queued webhook deliveries with the given number
+// of workers until ctx is done. Deliveries are claimed with FOR UPDATE SKIP
+// LOCKED, so every instance can run workers.
+func (cfg *apiConfig) runWebhookDeliveries(ctx context.Context, workers int) {
+	for range workers {
+		cfg.startWorker(func() {
+			ticker := time.NewTicker(webhookPollInterval)
+			defer ticker.Stop()
+			for {
+				for ctx.Err() == nil {
+					cfg.heartbeats.Beat("webhooks", webhookPollInterval, time.Now())
+					delivered, err := cfg.deliverNextWebhook(context.WithoutCancel(ctx))
+					if err != nil {
+						log.Printf("Error delivering webhook: %s", err)
+						break
+					}
+					if !delivered {
+						break
+					}
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		})
+	}
+}
+
+// deliverNextWebhook makes one attempt at the next due delivery and reports
+// whether there was one. Failed attempts are retried with exponential
+// backoff until webhooks.MaxAttempts.
+func (cfg *apiConfig) deliverNextWebhook(ctx context.Context) (bool, error) {
+	now := time.Now().UTC()
+	d, err := cfg.dbQueries.ClaimWebhookDelivery(ctx, database.ClaimWebhookDeliveryParams{Now: now, LeaseUntil: now.Add(webhookLease)})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	result := database.RecordWebhookDeliveryAttemptParams{ID: d.ID, Status: deliverySucceeded, NextAttemptAt: now}
+	endpoint, err := cfg.dbQueries.GetWebhookEndpointByID(ctx, d.EndpointID)
+	if err != nil {
+		return true, err
+	}
+	status, body, err := sendWebhook(ctx, endpoint, d)
+	if status != 0 {
+		result.ResponseStatus = sql.NullInt32{Int32: int32(status), Valid: true}
+		// Users only see the status of their own endpoints' responses, so
+		// the delivery log can't be used to read pages off other servers.
+		if !endpoint.UserID.Valid {
+			result.ResponseBody = sql.NullString{String: body, Valid: true}
+		}
+	}
+	if err == nil && (status < 200 || status > 299) {
+		err = fmt.Errorf("endpoint responded with status %d", status)
+	}
+	if err != nil {
+		result.Error = sql.NullString{String: err.Error(), Valid: true}
+		result.Status = deliveryPending
+		result.NextAttemptAt = time.Now().UTC().Add(webhooks.Backoff(int(d.Attempts)))
+		if d.Attempts >= webhooks.MaxAttempts || !endpoint.Active {
+			result.Status = deliveryFailed
+		}
+	}
+	cfg.metrics.WebhookDeliveries.WithLabelValues(result.Status).Inc()
+	return true, cfg.dbQueries.RecordWebhookDeliveryAttempt(ctx, result)
+}
+
+// sendWebhook posts a delivery to its endpoint, returning the response status
+// and the start of the response body.
+func sendWebhook(ctx context.Context, endpoint database.WebhookEndpoint, d database.WebhookDelivery) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint.Url, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks/1.0")
+	req.Header.Set(webhooks.EventHeader, d.EventType)
+	req.Header.Set(webhooks.DeliveryHeader, d.ID.String())
+	req.Header.Set(webhooks.TimestampHeader, fmt.Sprint(now.Unix()))
+	req.Header.Set(webhooks.SignatureHeader, auth.SignWebhook(endpoint.Secret, now, d.Payload))
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBody))
+	return resp.StatusCode, string(body), nil
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/stream"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/google/uuid"
+)
+
+const (
+	wsPingInterval = 30 * time.Second
+	wsPongTimeout  = 10 * time.Second
+	wsWriteTimeout = 10 * time.Second
+	wsSendBuffer   = 64
+	wsTopicBuffer  = 64
+	wsReadLimit    = 4096
+	wsMaxTopics    = 20
+)
+
+// Messages sent by the client: {"type":"subscribe","topic":"timeline"},
+// {"type":"unsubscribe","topic":"timeline"} and
+// {"type":"authenticate","token":"<fresh access token>"}.
+type wsClientMessage struct {
+	Type  string `json:"type"`
+	Topic string `json:"topic,omitempty"`
+	Token string `json:"token,omitempty"`
+}
+
+type wsServerMessage struct {
+	Type      string     `json:"type"`
+	Topic     string     `json:"topic,omitempty"`
+	Event     any        `json:"event,omitempty"`
+	Message   string     `json:"message,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type wsClient struct {
+	cfg       *apiConfig
+	conn      *websocket.Conn
+	userID    uuid.UUID
+	send      chan wsServerMessage
+	expiry    *time.Timer
+	closeOnce sync.Once
+
+	mu     sync.Mutex
+	topics map[string]func()
+}
+
+// handlerWebSocket serves live timelines and notifications over a single
+// WebSocket. The access token comes from the Authorization header or, for
+// browsers that can't set it, the access_token query parameter; the
+// connection is closed when it expires unless the client sends a fresh one.
+func (cfg *apiConfig) handlerWebSocket(w http.ResponseWriter, req *http.Request) {
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		token = req.URL.Query().Get("access_token")
+	}
+	if token == "" {
+		respondWithError(w, 401, "Error fetching authorization token")
+		return
+	}
+	userID, expiresAt, err := auth.ValidateJWTWithExpiry(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization token")
+		return
+	}
+	clearDeadlines(w)
+	conn, err := websocket.Accept(w, req, nil)
+	if err != nil {
+		return
+	}
+	conn.SetReadLimit(wsReadLimit)
+	// Hijacked connections aren't tracked by server.Shutdown.
+	cfg.streams.Add(1)
+	defer cfg.streams.Done()
+
+	ctx, cancel := context.WithCancel(req.Context())
+	c := &wsClient{
+		cfg:    cfg,
+		conn:   conn,
+		userID: userID,
+		send:   make(chan wsServerMessage, wsSendBuffer),
+		topics: map[string]func(){},
+	}
+	c.expiry = time.AfterFunc(time.Until(expiresAt), func() {
+		c.close(websocket.StatusPolicyViolation, "token expired")
+	})
+	defer func() {
+		c.expiry.Stop()
+		c.unsubscribeAll()
+		cancel()
+		conn.CloseNow()
+	}()
+	go c.writeLoop(ctx)
+	go c.pingLoop(ctx)
+	go func() {
+		select {
+		case <-cfg.closing:
+			c.close(websocket.StatusGoingAway, "server shutting down")
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		msg := wsClientMessage{}
+		if err := wsjson.Read(ctx, conn, &msg); err != nil {
+			return
+		}
+		switch msg.Type {
+		case "subscribe":
+			c.subscribe(ctx, msg.Topic)
+		case "unsubscribe":
+			c.unsubscribe(msg.Topic)
+		case "authenticate":
+			c.authenticate(msg.Token)
+		default:
+			c.enqueue(wsServerMessage{Type: "error", Message: "Unknown message type"})
+		}
+	}
+}
+
+// close starts the closing handshake, which also ends the read loop.
+func (c *wsClient) close(code websocket.StatusCode, reason string) {
+	c.closeOnce.Do(func() {
+		go c.conn.Close(code, reason)
+	})
+}
+
+// enqueue queues a message for the client. A client that doesn't read fast
+// enough to keep the queue from filling up is disconnected.
+func (c *wsClient) enqueue(msg wsServerMessage) {
+	select {
+	case c.send <- msg:
+	default:
+		c.close(websocket.StatusPolicyViolation, "client too slow")
+	}
+}
+
+func (c *wsClient) writeLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-c.send:
+			writeCtx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+			err := wsjson.Write(writeCtx, c.conn, msg)
+			cancel()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *wsClient) pingLoop(ctx context.Context) {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, wsPongTimeout)
+			err := c.conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				c.close(websocket.StatusPolicyViolation, "pong timeout")
+				return
+			}
+		}
+	}
+}
+
+func (c *wsClient) authenticate(token string) {
+	userID, expiresAt, err := auth.ValidateJWTWithExpiry(token, c.cfg.secret)
+	if err != nil || userID != c.userID {
+		c.enqueue(wsServerMessage{Type: "error", Message: "Invalid authorization token"})
+		return
+	}
+	c.expiry.Reset(time.Until(expiresAt))
+	c.enqueue(wsServerMessage{Type: "authenticated", ExpiresAt: &expiresAt})
+}
+
+// subscribe starts forwarding a topic: "timeline" for new and deleted chirps,
+// "notifications" for the user's own notifications, or "chirp:<chirpID>" for
+// changes to a single chirp.
+func (c *wsClient) subscribe(ctx context.Context, topic string) {
+	c.mu.Lock()
+	_, exists := c.topics[topic]
+	count := len(c.topics)
+	c.mu.Unlock()
+	if exists {
+		c.enqueue(wsServerMessage{Type: "subscribed", Topic: topic})
+		return
+	}
+	if count >= wsMaxTopics {
+		c.enqueue(wsServerMessage{Type: "error", Topic: topic, Message: "Too many subscriptions"})
+		return
+	}
+
+	var unsubscribe func()
+	switch {
+	case topic == "timeline":
+		hidden, err := c.cfg.dbQueries.ListHiddenAuthors(ctx, c.userID)
+		if err != nil {
+			c.enqueue(wsServerMessage{Type: "error", Topic: topic, Message: "Error subscribing"})
+			return
+		}
+		sub := c.cfg.chirpEvents.Subscribe(stream.Filter{HiddenAuthors: hidden, ViewerID: c.userID}.Match, wsTopicBuffer)
+		go forward(c, topic, sub)
+		unsubscribe = func() { c.cfg.chirpEvents.Unsubscribe(sub) }
+	case topic == "notifications":
+		sub := c.cfg.notificationEvents.Subscribe(func(n stream.Notification) bool {
+			return n.UserID == c.userID
+		}, wsTopicBuffer)
+		go forward(c, topic, sub)
+		unsubscribe = func() { c.cfg.notificationEvents.Unsubscribe(sub) }
+	case strings.HasPrefix(topic, "chirp:"):
+		chirpID, err := uuid.Parse(strings.TrimPrefix(topic, "chirp:"))
+		if err != nil {
+			c.enqueue(wsServerMessage{Type: "error", Topic: topic, Message: "Invalid ChirpID"})
+			return
+		}
+		if chirp, err := c.cfg.dbQueries.GetChirp(ctx, chirpID); err != nil || !canView(chirp, c.userID, true) {
+			c.enqueue(wsServerMessage{Type: "error", Topic: topic, Message: "Chirp doesn't exist"})
+			return
+		}
+		sub := c.cfg.chirpEvents.Subscribe(stream.Filter{ChirpID: chirpID, ViewerID: c.userID}.Match, wsTopicBuffer)
+		go forward(c, topic, sub)
+		unsubscribe = func() { c.cfg.chirpEvents.Unsubscribe(sub) }
+	default:
+		c.enqueue(wsServerMessage{Type: "error", Topic: topic, Message: "Unknown topic"})
+		return
+	}
+
+	c.mu.Lock()
+	c.topics[topic] = unsubscribe
+	c.mu.Unlock()
+	c.enqueue(wsServerMessage{Type: "subscribed", Topic: topic})
+}
+
+func (c *wsClient) unsubscribe(topic string) {
+	c.mu.Lock()
+	unsubscribe, ok := c.topics[topic]
+	delete(c.topics, topic)
+	c.mu.Unlock()
+	if ok {
+		unsubscribe()
+	}
+	c.enqueue(wsServerMessage{Type: "unsubscribed", Topic: topic})
+}
+
+func (c *wsClient) unsubscribeAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for topic, unsubscribe := range c.topics {
+		unsubscribe()
+		delete(c.topics, topic)
+	}
+}
+
+func forward[T any](c *wsClient, topic string, sub *stream.Subscription[T]) {
+	for e := range sub.C {
+		c.enqueue(wsServerMessage{Type: "event", Topic: topic, Event: e})
+	}
+	if sub.Dropped() {
+		c.close(websocket.StatusPolicyViolation, "client too slow")
+	}
+}